@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"chat-room/pkg/common/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginUuid 获取鉴权中间件写入上下文的当前登录用户uuid
+func loginUuid(c *gin.Context) string {
+	return c.GetString(constant.CTX_USER_UUID)
+}
+
+// checkUuid
+//
+//	@Description: 请求中携带的用户uuid必须是当前登录用户，未携带时直接使用登录用户
+//	@param c
+//	@param uuid
+//	@return string
+//	@return bool
+func checkUuid(c *gin.Context, uuid string) (string, bool) {
+	login := loginUuid(c)
+	if uuid != "" && uuid != login {
+		return "", false
+	}
+	return login, true
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+
+	"chat-room/internal/service"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConversations
+//  @Description: 获取会话列表，包含最后一条消息、未读数以及免打扰、置顶设置
+//  @param c
+func GetConversations(c *gin.Context) {
+	conversations, err := service.ConversationService.GetConversations(loginUuid(c))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(conversations))
+}
+
+// ModifyConversation
+//  @Description: 修改会话的免打扰、置顶设置
+//  @param c
+func ModifyConversation(c *gin.Context) {
+	var conversationRequest request.ConversationRequest
+	if err := c.ShouldBindJSON(&conversationRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	err := service.ConversationService.ModifyConversation(loginUuid(c), c.Param("conversationId"), conversationRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
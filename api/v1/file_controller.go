@@ -31,7 +31,11 @@ func SaveFile(c *gin.Context) {
 	// 文件前缀
 	namePreffix := uuid.NewString()
 
-	userUuid := c.PostForm("uuid")
+	userUuid, ok := checkUuid(c, c.PostForm("uuid"))
+	if !ok {
+		c.JSON(http.StatusOK, response.FailMsg("无权修改其他用户的头像"))
+		return
+	}
 	file, _ := c.FormFile("file") // 获取上传文件的基本内容
 	fileName := file.Filename
 	index := strings.LastIndex(fileName, ".")
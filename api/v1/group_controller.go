@@ -2,7 +2,9 @@ package v1
 
 import (
 	"chat-room/internal/model"
+	"chat-room/internal/server"
 	"chat-room/internal/service"
+	"chat-room/pkg/common/request"
 	"chat-room/pkg/common/response"
 	"net/http"
 
@@ -11,7 +13,11 @@ import (
 
 // GetGroup 获取群聊列表
 func GetGroup(c *gin.Context) {
-	uuid := c.Param("uuid")
+	uuid, ok := checkUuid(c, c.Param("uuid"))
+	if !ok {
+		c.JSON(http.StatusOK, response.FailMsg("无权查看其他用户的群聊"))
+		return
+	}
 	groups, err := service.GroupService.GetGroups(uuid)
 	if err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
@@ -22,7 +28,11 @@ func GetGroup(c *gin.Context) {
 
 // SaveGroup 保存分组列表
 func SaveGroup(c *gin.Context) {
-	uuid := c.Param("uuid")
+	uuid, ok := checkUuid(c, c.Param("uuid"))
+	if !ok {
+		c.JSON(http.StatusOK, response.FailMsg("无权以其他用户身份创建群聊"))
+		return
+	}
 	var group model.Group
 	/*
 		绑定payload参数到group中
@@ -36,7 +46,11 @@ func SaveGroup(c *gin.Context) {
 
 // JoinGroup 加入群聊
 func JoinGroup(c *gin.Context) {
-	userUuid := c.Param("userUuid")
+	userUuid, ok := checkUuid(c, c.Param("userUuid"))
+	if !ok {
+		c.JSON(http.StatusOK, response.FailMsg("无权替其他用户加入群聊"))
+		return
+	}
 	groupUuid := c.Param("groupUuid")
 	err := service.GroupService.JoinGroup(groupUuid, userUuid)
 	if err != nil {
@@ -46,9 +60,218 @@ func JoinGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(nil))
 }
 
-// GetGroupUsers 获取群聊组内成员信息
+// GetGroupUsers 获取群聊组内成员信息，包含成员的角色
 func GetGroupUsers(c *gin.Context) {
-	groupUuid := c.Param("uuid")
-	users := service.GroupService.GetUserIdByGroupUuid(groupUuid)
+	users, err := service.GroupService.GetMembers(loginUuid(c), c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
 	c.JSON(http.StatusOK, response.SuccessMsg(users))
 }
+
+// ModifyGroup 修改群名称、群公告
+func ModifyGroup(c *gin.Context) {
+	var groupRequest request.GroupRequest
+	if err := c.ShouldBindJSON(&groupRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	err := service.GroupService.ModifyGroup(loginUuid(c), c.Param("groupUuid"), groupRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// InviteMember 邀请用户入群
+func InviteMember(c *gin.Context) {
+	err := service.GroupService.InviteMember(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// AddAdmin 设置管理员
+func AddAdmin(c *gin.Context) {
+	setAdmin(c, true)
+}
+
+// RemoveAdmin 取消管理员
+func RemoveAdmin(c *gin.Context) {
+	setAdmin(c, false)
+}
+
+func setAdmin(c *gin.Context, admin bool) {
+	err := service.GroupService.SetAdmin(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"), admin)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// MuteMember 禁言群成员，可以指定禁言时长
+func MuteMember(c *gin.Context) {
+	var muteRequest request.MuteRequest
+	if err := c.ShouldBindJSON(&muteRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	err := service.GroupService.MuteMember(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"), true, muteRequest.Duration)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// UnmuteMember 解除群成员禁言
+func UnmuteMember(c *gin.Context) {
+	err := service.GroupService.MuteMember(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"), false, 0)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// SetAllMuted 开启或关闭全员禁言
+func SetAllMuted(c *gin.Context) {
+	var allMuteRequest request.AllMuteRequest
+	if err := c.ShouldBindJSON(&allMuteRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	err := service.GroupService.SetAllMuted(loginUuid(c), c.Param("groupUuid"), allMuteRequest.Muted)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// LeaveGroup 退出群聊
+func LeaveGroup(c *gin.Context) {
+	if err := server.MyServer.LeaveGroup(loginUuid(c), c.Param("groupUuid")); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// KickMember 将成员移出群聊
+func KickMember(c *gin.Context) {
+	removeMember(c, false)
+}
+
+// BanMember 将成员移出群聊并禁止再次加入
+func BanMember(c *gin.Context) {
+	removeMember(c, true)
+}
+
+func removeMember(c *gin.Context, ban bool) {
+	err := server.MyServer.RemoveMember(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"), ban)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// UnbanMember 将用户移出群黑名单
+func UnbanMember(c *gin.Context) {
+	err := service.GroupService.UnbanMember(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// GetBans 获取群黑名单
+func GetBans(c *gin.Context) {
+	bans, err := service.GroupService.GetBans(loginUuid(c), c.Param("groupUuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(bans))
+}
+
+// TransferOwner 转让群主
+func TransferOwner(c *gin.Context) {
+	err := server.MyServer.TransferOwner(loginUuid(c), c.Param("groupUuid"), c.Param("userUuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// DissolveGroup 解散群聊
+func DissolveGroup(c *gin.Context) {
+	if err := server.MyServer.DissolveGroup(loginUuid(c), c.Param("groupUuid")); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// CreateInvite 创建群邀请链接
+func CreateInvite(c *gin.Context) {
+	var inviteRequest request.InviteRequest
+	if err := c.ShouldBindJSON(&inviteRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	invite, err := service.GroupService.CreateInvite(loginUuid(c), c.Param("groupUuid"), inviteRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(invite))
+}
+
+// GetInvites 获取群邀请链接
+func GetInvites(c *gin.Context) {
+	invites, err := service.GroupService.GetInvites(loginUuid(c), c.Param("groupUuid"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(invites))
+}
+
+// RevokeInvite 撤销群邀请链接
+func RevokeInvite(c *gin.Context) {
+	err := service.GroupService.RevokeInvite(loginUuid(c), c.Param("groupUuid"), c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// RedeemInvite 通过邀请链接加入群聊
+func RedeemInvite(c *gin.Context) {
+	group, err := service.GroupService.RedeemInvite(loginUuid(c), c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(group))
+}
+
+// GetInviteUses 查询通过邀请链接入群的记录，可以用token参数指定链接
+func GetInviteUses(c *gin.Context) {
+	uses, err := service.GroupService.GetInviteUses(loginUuid(c), c.Param("groupUuid"), c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(uses))
+}
@@ -2,7 +2,9 @@ package v1
 
 import (
 	"net/http"
+	"strconv"
 
+	"chat-room/internal/server"
 	"chat-room/internal/service"
 	"chat-room/pkg/common/request"
 	"chat-room/pkg/common/response"
@@ -12,7 +14,7 @@ import (
 )
 
 // GetMessage
-//  @Description: 获取消息列表 消息保存在数据库中 打开聊天窗口就请求一次消息表
+//  @Description: 获取消息列表 消息保存在数据库中 打开聊天窗口就请求一次消息表，支持before/after游标分页
 //  @param c
 func GetMessage(c *gin.Context) {
 	log.Logger.Info(c.Query("uuid"))
@@ -23,7 +25,7 @@ func GetMessage(c *gin.Context) {
 	}
 	log.Logger.Info("messageRequest params: ", log.Any("messageRequest", messageRequest))
 
-	messages, err := service.MessageService.GetMessages(messageRequest)
+	messages, err := service.MessageService.GetMessages(loginUuid(c), messageRequest)
 	if err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
 		return
@@ -31,3 +33,277 @@ func GetMessage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response.SuccessMsg(messages))
 }
+
+// SyncMessage
+//  @Description: 离线消息同步，客户端上报每个会话已收到的最大序号，返回缺失的消息
+//  @param c
+func SyncMessage(c *gin.Context) {
+	var syncRequest request.SyncRequest
+	if err := c.ShouldBindJSON(&syncRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	result, err := service.MessageService.SyncMessages(loginUuid(c), syncRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(result))
+}
+
+// RecallMessage
+//  @Description: 撤回消息，会话中的在线设备会收到撤回事件
+//  @param c
+func RecallMessage(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	if err = server.MyServer.Recall(loginUuid(c), "", msgId); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// DeleteMessage
+//  @Description: 删除消息，仅对自己不可见
+//  @param c
+func DeleteMessage(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	if err = server.MyServer.Delete(loginUuid(c), "", msgId); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// EditMessage
+//  @Description: 编辑自己发送的文本消息，会话中的在线设备会收到编辑事件
+//  @param c
+func EditMessage(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+	var editRequest request.EditMessageRequest
+	if err = c.ShouldBindJSON(&editRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	if err = server.MyServer.Edit(loginUuid(c), "", msgId, editRequest.Content); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// GetEditHistory
+//  @Description: 获取消息的编辑历史
+//  @param c
+func GetEditHistory(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	edits, err := service.MessageService.GetEditHistory(loginUuid(c), msgId)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(edits))
+}
+
+// GetThread
+//  @Description: 获取话题的根消息和回复，回复支持before/after游标分页
+//  @param c
+func GetThread(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+	var messageRequest request.MessageRequest
+	_ = c.BindQuery(&messageRequest)
+
+	thread, err := service.MessageService.GetThread(loginUuid(c), msgId, messageRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(thread))
+}
+
+// GetMentions
+//  @Description: 获取@我的消息，支持按群过滤以及before/after游标分页
+//  @param c
+func GetMentions(c *gin.Context) {
+	var messageRequest request.MessageRequest
+	_ = c.BindQuery(&messageRequest)
+
+	page, err := service.MentionService.GetMentions(loginUuid(c), messageRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(page))
+}
+
+// SearchMessage
+//  @Description: 在自己所在的会话中搜索消息，返回高亮摘要，支持before游标分页
+//  @param c
+func SearchMessage(c *gin.Context) {
+	var searchRequest request.SearchRequest
+	if err := c.BindQuery(&searchRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	page, err := service.SearchService.Search(loginUuid(c), searchRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(page))
+}
+
+// ForwardMessage
+//  @Description: 转发消息到指定的用户或群，支持逐条转发和合并转发
+//  @param c
+func ForwardMessage(c *gin.Context) {
+	var forwardRequest request.ForwardRequest
+	if err := c.ShouldBindJSON(&forwardRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	messages, err := server.MyServer.Forward(loginUuid(c), "", forwardRequest)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(messages))
+}
+
+// GetChatRecords
+//  @Description: 展开聊天记录，返回其中的原消息
+//  @param c
+func GetChatRecords(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	records, err := service.ForwardService.GetRecords(loginUuid(c), msgId)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(records))
+}
+
+// AddReaction
+//  @Description: 对消息添加表情回应
+//  @param c
+func AddReaction(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+	var reactionRequest request.ReactionRequest
+	if err = c.ShouldBindJSON(&reactionRequest); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	if err = server.MyServer.React(loginUuid(c), "", msgId, reactionRequest.Emoji, true); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// RemoveReaction
+//  @Description: 取消对消息的表情回应
+//  @param c
+func RemoveReaction(c *gin.Context) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	if err = server.MyServer.React(loginUuid(c), "", msgId, c.Param("emoji"), false); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// PinMessage
+//  @Description: 置顶消息
+//  @param c
+func PinMessage(c *gin.Context) {
+	pinMessage(c, true)
+}
+
+// UnpinMessage
+//  @Description: 取消置顶消息
+//  @param c
+func UnpinMessage(c *gin.Context) {
+	pinMessage(c, false)
+}
+
+func pinMessage(c *gin.Context, pin bool) {
+	msgId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息id错误"))
+		return
+	}
+
+	if err = server.MyServer.Pin(loginUuid(c), "", msgId, pin); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))
+}
+
+// GetPins
+//  @Description: 获取会话中置顶的消息
+//  @param c
+func GetPins(c *gin.Context) {
+	pins, err := service.PinService.GetPins(loginUuid(c), c.Query("conversationId"))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(pins))
+}
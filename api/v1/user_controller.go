@@ -2,11 +2,15 @@ package v1
 
 import (
 	"net/http"
+	"strings"
 
 	"chat-room/internal/model"
+	"chat-room/internal/server"
 	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
 	"chat-room/pkg/common/request"
 	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/token"
 	"chat-room/pkg/global/log"
 
 	"github.com/gin-gonic/gin"
@@ -25,12 +29,47 @@ func Login(c *gin.Context) {
 	log.Logger.Debug("user", log.Any("user", user))
 
 	// 响应 web端没有缓存头像文件 登录的时候是以文件名来请求服务器获取的
-	if service.UserService.Login(&user) {
-		c.JSON(http.StatusOK, response.SuccessMsg(user))
+	if !service.UserService.Login(&user) {
+		c.JSON(http.StatusOK, response.FailMsg("Login failed"))
 		return
 	}
 
-	c.JSON(http.StatusOK, response.FailMsg("Login failed"))
+	accessToken, refreshToken, err := token.GenerateTokenPair(user.Uuid)
+	if err != nil {
+		log.Logger.Error("generate token error", log.Any("generate token error", err))
+		c.JSON(http.StatusOK, response.FailMsg("Login failed"))
+		return
+	}
+	user.Password = ""
+	c.JSON(http.StatusOK, response.SuccessMsg(response.LoginResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}))
+}
+
+// RefreshToken
+//  @Description: 使用refreshToken换取新的token
+//  @param c
+func RefreshToken(c *gin.Context) {
+	var refreshRequest request.RefreshTokenRequest
+	_ = c.ShouldBindJSON(&refreshRequest)
+
+	claims, err := token.ParseToken(refreshRequest.RefreshToken, constant.REFRESH_TOKEN)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, err.Error()))
+		return
+	}
+
+	accessToken, refreshToken, err := token.GenerateTokenPair(claims.Uuid)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(response.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}))
 }
 
 // Register
@@ -55,6 +94,7 @@ func ModifyUserInfo(c *gin.Context) {
 	var user model.User
 	_ = c.ShouldBindJSON(&user)
 	log.Logger.Debug("user", log.Any("user", user))
+	user.Uuid = loginUuid(c) // 只能修改自己的信息
 	if err := service.UserService.ModifyUserInfo(&user); err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error()))
 		return
@@ -79,17 +119,51 @@ func GetUserOrGroupByName(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserOrGroupByName(name)))
 }
 
+// GetUserList
+//  @Description: 获取当前登录用户的好友列表
+//  @param c
 func GetUserList(c *gin.Context) {
-	uuid := c.Query("uuid")
+	uuid, ok := checkUuid(c, c.Query("uuid"))
+	if !ok {
+		c.JSON(http.StatusOK, response.FailMsg("无权查看其他用户的好友"))
+		return
+	}
 	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserList(uuid)))
 }
 
+// GetPresence
+//  @Description: 批量查询用户的在线状态(online/away/offline)以及最后在线时间 ?uuids=uuid1,uuid2
+//  @Description: 只能查询自己、好友以及同群成员，其他用户不会出现在结果中
+//  @param c
+func GetPresence(c *gin.Context) {
+	userUuid := loginUuid(c)
+	visible := map[string]bool{userUuid: true}
+	for _, uuid := range service.UserService.GetPresenceAudience(userUuid) {
+		visible[uuid] = true
+	}
+
+	statuses := make([]response.PresenceResponse, 0)
+	for _, uuid := range strings.Split(c.Query("uuids"), ",") {
+		if !visible[uuid] {
+			continue
+		}
+		status, err := server.MyServer.Status(uuid)
+		if err != nil {
+			c.JSON(http.StatusOK, response.FailMsg(err.Error()))
+			return
+		}
+		statuses = append(statuses, response.PresenceResponse{Uuid: uuid, Status: status.Status, LastSeen: status.LastSeen})
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(statuses))
+}
+
 // AddFriend
 //  @Description: 添加好友
 //  @param c
 func AddFriend(c *gin.Context) {
 	var userFriendRequest request.FriendRequest
 	_ = c.ShouldBindJSON(&userFriendRequest)
+	userFriendRequest.Uuid = loginUuid(c) // 申请人即当前登录用户
 
 	err := service.UserService.AddFriend(&userFriendRequest)
 	if nil != err {
@@ -2,10 +2,14 @@ package main
 
 import (
 	"chat-room/config"
-	"chat-room/internal/kafka"
+	"chat-room/internal/bus"
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/presence"
 	"chat-room/internal/router"
+	"chat-room/internal/search"
 	"chat-room/internal/server"
-	"chat-room/pkg/common/constant"
+	"chat-room/internal/service"
+	"chat-room/pkg/common/token"
 	"chat-room/pkg/global/log"
 	"net/http"
 	"time"
@@ -23,13 +27,41 @@ func main() {
 	log.InitLogger(conf.Log.Path, conf.Log.Level)
 	log.Logger.Info("config", log.Any("config", conf))
 
-	// 使用kafka作为消息队列，可以分布式扩展消息聊天程序
-	if conf.MsgChannelType.ChannelType == constant.KAFKA {
-		kafka.InitProducer(conf.MsgChannelType.KafkaTopic, conf.MsgChannelType.KafkaHosts)
-		kafka.InitConsumer(conf.MsgChannelType.KafkaHosts)
-		go kafka.ConsumerMsg(server.ConsumerKafkaMsg)
+	// 未配置签名密钥时拒绝启动，避免使用空密钥或公开的默认密钥签发token
+	if err := token.Init(conf.Jwt); err != nil {
+		log.Logger.Error("init token error", log.Any("init token error", err.Error()))
+		return
 	}
 
+	// 根据配置选择消息总线：gochannel为单机使用，kafka作为消息队列，可以分布式扩展消息聊天程序
+	msgBus, err := bus.New(conf)
+	if err != nil {
+		log.Logger.Error("init message bus error", log.Any("init message bus error", err))
+		return
+	}
+	defer msgBus.Close()
+	if err = server.MyServer.SetBus(msgBus); err != nil {
+		log.Logger.Error("subscribe message bus error", log.Any("subscribe message bus error", err))
+		return
+	}
+
+	// 在线状态注册表，多节点部署时据此将单聊消息定向投递到接收人所在的节点
+	registry, err := presence.New(conf)
+	if err != nil {
+		log.Logger.Error("init presence registry error", log.Any("init presence registry error", err))
+		return
+	}
+	defer registry.Close()
+	server.MyServer.SetPresence(registry)
+
+	// 消息检索引擎，local引擎启动时从数据库加载消息建立索引
+	engine, err := search.New(conf, pool.GetDB())
+	if err != nil {
+		log.Logger.Error("init search engine error", log.Any("init search engine error", err))
+		return
+	}
+	service.SearchService.SetEngine(engine)
+
 	log.Logger.Info("start server", log.String("start", "start web sever..."))
 
 	go server.MyServer.Start()
@@ -43,7 +75,7 @@ func main() {
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
-	err := s.ListenAndServe()
+	err = s.ListenAndServe()
 	if nil != err {
 		log.Logger.Error("server error", log.Any("serverError", err))
 	}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"sync"
 
 	"github.com/spf13/viper"
@@ -11,10 +12,17 @@ import (
 // @Description: 全部配置
 type TomlConfig struct {
 	AppName        string
+	NodeId         string // 节点标识，分布式部署时每个节点需不同，未配置时使用主机名
 	MySQL          MySQLConfig
+	Redis          RedisConfig
 	Log            LogConfig
 	StaticPath     PathConfig
 	MsgChannelType MsgChannelType
+	Presence       PresenceConfig
+	Jwt            JwtConfig
+	Message        MessageConfig
+	Search         SearchConfig
+	Group          GroupConfig
 }
 
 // MySQLConfig MySQL配置
@@ -30,6 +38,14 @@ type MySQLConfig struct {
 	MaxIdle     int // 最大空闲连接数
 }
 
+// RedisConfig
+// @Description: redis配置，消息队列类型或在线状态注册表类型为redis时使用
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
 // LogConfig
 // @Description: 日志保存地址
 type LogConfig struct {
@@ -47,10 +63,50 @@ type PathConfig struct {
 // @Description: 消息队列类型及其消息队列相关信息
 // @Description: gochannel为单机使用go默认的channel进行消息传递
 // @Description: kafka是使用kafka作为消息队列，可以分布式扩展消息聊天程序
+// @Description: redis是使用redis发布订阅进行节点间的消息传递，比kafka更轻量
 type MsgChannelType struct {
-	ChannelType string
-	KafkaHosts  string
-	KafkaTopic  string
+	ChannelType  string
+	KafkaHosts   string
+	KafkaTopic   string
+	KafkaGroupId string // 消费组，每个节点需要收到全部消息，未配置时为 appName-nodeId
+	RedisChannel string
+}
+
+// JwtConfig
+// @Description: 登录token签发配置
+// @Description: accessToken用于访问接口及建立websocket连接，过期后使用refreshToken换取新的token
+type JwtConfig struct {
+	Secret        string
+	Issuer        string
+	AccessExpire  int64 // accessToken有效期，单位秒
+	RefreshExpire int64 // refreshToken有效期，单位秒
+}
+
+// PresenceConfig
+// @Description: 在线状态注册表配置
+// @Description: local为单机使用；redis在多个节点间共享用户所在的节点，用于单聊消息的定向投递
+type PresenceConfig struct {
+	Registry string
+	NodeTTL  int // 节点存活标记有效期，单位秒，节点宕机超过该时间后其在线记录失效
+	Debounce int // 状态变化推送的防抖时间，单位秒，该时间内断线重连不会通知好友
+}
+
+// MessageConfig 消息相关配置
+type MessageConfig struct {
+	RecallWindow int // 消息发出后允许撤回的时间，单位秒
+	MaxPins      int // 每个会话最多置顶的消息数
+}
+
+// SearchConfig
+// @Description: 消息检索配置
+// @Description: mysql使用messages表上的FULLTEXT(ngram)索引；local为进程内索引，启动时从数据库加载，只适合单机部署
+type SearchConfig struct {
+	Engine string
+}
+
+// GroupConfig 群组相关配置
+type GroupConfig struct {
+	OpenJoin bool // 是否允许通过群uuid直接加入群聊，关闭后只能通过邀请链接或成员邀请入群
 }
 
 var c TomlConfig
@@ -66,6 +122,8 @@ func init() {
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("..")
 	viper.AutomaticEnv()
+	// jwt签名密钥不提交到配置文件中，可以通过环境变量设置
+	_ = viper.BindEnv("jwt.secret", "JWT_SECRET")
 
 	// 读配置
 	err := viper.ReadInConfig()
@@ -74,6 +132,9 @@ func init() {
 	}
 
 	_ = viper.Unmarshal(&c)
+	if c.NodeId == "" {
+		c.NodeId, _ = os.Hostname()
+	}
 }
 
 func GetConfig() TomlConfig {
@@ -0,0 +1,49 @@
+package bus
+
+import (
+	"chat-room/config"
+	"chat-room/internal/dao/rdb"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+)
+
+// Handler 订阅消息的回调函数
+type Handler func(data []byte)
+
+// Bus
+// @Description: 消息总线，屏蔽不同消息队列的差异
+// @Description: 客户端发来的消息统一发布到总线，再由订阅方(server)投递给在线用户
+// @Description: 新增消息队列只需实现该接口并在 New 中注册对应的类型即可
+type Bus interface {
+	// Publish 发布一条消息，key为消息所属的会话，同一key的消息保证按发布顺序投递
+	Publish(key string, data []byte) error
+	// PublishTo 只发布给指定节点，用于将单聊消息定向投递到接收人所在的节点
+	PublishTo(node string, key string, data []byte) error
+	// Subscribe 订阅消息，总线上的每条消息都会回调 handler
+	Subscribe(handler Handler) error
+	// Close 关闭总线，释放连接
+	Close() error
+}
+
+// New
+//
+//	@Description: 根据配置中的消息队列类型创建对应的消息总线
+//	@param conf
+//	@return Bus
+//	@return error
+func New(conf config.TomlConfig) (Bus, error) {
+	switch conf.MsgChannelType.ChannelType {
+	case constant.GO_CHANNEL, "":
+		return NewChannelBus(), nil
+	case constant.KAFKA:
+		return newKafkaBus(conf)
+	case constant.REDIS:
+		client, err := rdb.NewClient(conf.Redis)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisBus(client, conf.MsgChannelType.RedisChannel, conf.NodeId), nil
+	default:
+		return nil, errors.New("不支持的消息队列类型: " + conf.MsgChannelType.ChannelType)
+	}
+}
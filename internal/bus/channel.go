@@ -0,0 +1,79 @@
+package bus
+
+import (
+	"sync"
+
+	"chat-room/pkg/errors"
+)
+
+// channelBus
+// @Description: 基于go channel的进程内消息总线，单机部署或测试时使用
+type channelBus struct {
+	ch       chan []byte
+	done     chan struct{}
+	once     sync.Once
+	mutex    sync.RWMutex
+	handlers []Handler
+}
+
+// NewChannelBus 创建进程内消息总线
+func NewChannelBus() Bus {
+	b := &channelBus{
+		ch:   make(chan []byte, 1024),
+		done: make(chan struct{}),
+	}
+	go b.dispatch()
+	return b
+}
+
+// Publish 进程内只有一个消费协程，天然保证有序，无需使用key
+func (b *channelBus) Publish(key string, data []byte) error {
+	select {
+	case <-b.done:
+		return errors.New("消息总线已关闭")
+	default:
+	}
+
+	select {
+	case b.ch <- data:
+		return nil
+	case <-b.done:
+		return errors.New("消息总线已关闭")
+	}
+}
+
+// PublishTo 进程内只有本节点
+func (b *channelBus) PublishTo(node string, key string, data []byte) error {
+	return b.Publish(key, data)
+}
+
+func (b *channelBus) Subscribe(handler Handler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+func (b *channelBus) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+	})
+	return nil
+}
+
+// dispatch 将发布的消息依次回调给所有订阅者
+func (b *channelBus) dispatch() {
+	for {
+		select {
+		case data := <-b.ch:
+			b.mutex.RLock()
+			handlers := b.handlers
+			b.mutex.RUnlock()
+			for _, handler := range handlers {
+				handler(data)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
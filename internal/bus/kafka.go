@@ -0,0 +1,50 @@
+package bus
+
+import (
+	"chat-room/config"
+	"chat-room/internal/kafka"
+)
+
+// kafkaBus
+// @Description: 基于kafka的消息总线，多个节点共享同一个topic，可以分布式扩展消息聊天程序
+// @Description: 每个节点另外消费 topic.节点id 用于定向投递，需要kafka开启自动创建topic或提前创建
+type kafkaBus struct {
+	topic string
+	node  string
+}
+
+func newKafkaBus(conf config.TomlConfig) (Bus, error) {
+	channel := conf.MsgChannelType
+	if err := kafka.InitProducer(channel.KafkaTopic, channel.KafkaHosts); err != nil {
+		return nil, err
+	}
+
+	groupId := channel.KafkaGroupId
+	if groupId == "" {
+		groupId = conf.AppName + "-" + conf.NodeId
+	}
+	if err := kafka.InitConsumer(channel.KafkaHosts, groupId); err != nil {
+		kafka.Close()
+		return nil, err
+	}
+	return &kafkaBus{topic: channel.KafkaTopic, node: conf.NodeId}, nil
+}
+
+func (b *kafkaBus) Publish(key string, data []byte) error {
+	return kafka.Send(key, data)
+}
+
+func (b *kafkaBus) PublishTo(node string, key string, data []byte) error {
+	return kafka.SendTo(b.topic+"."+node, key, data)
+}
+
+func (b *kafkaBus) Subscribe(handler Handler) error {
+	go kafka.ConsumerMsg(kafka.ConsumerCallback(handler), b.topic, b.topic+"."+b.node)
+	return nil
+}
+
+func (b *kafkaBus) Close() error {
+	kafka.CloseConsumer()
+	kafka.Close()
+	return nil
+}
@@ -0,0 +1,70 @@
+package bus
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisBus
+// @Description: 基于redis发布订阅的消息总线，适合不想引入kafka的中等规模分布式部署
+// @Description: 发布订阅不做持久化，节点宕机期间的消息不会补发
+// @Description: 每个节点同时订阅公共channel和 channel:节点id，后者用于定向投递
+type redisBus struct {
+	client  *redis.Client
+	channel string
+	node    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pubSubs []*redis.PubSub
+}
+
+// NewRedisBus 使用已创建好的redis客户端创建消息总线，所有节点订阅同一个channel
+func NewRedisBus(client *redis.Client, channel string, node string) Bus {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &redisBus{
+		client:  client,
+		channel: channel,
+		node:    node,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Publish 每个节点只有一个订阅协程，同一channel内的消息按发布顺序投递，无需使用key
+func (b *redisBus) Publish(key string, data []byte) error {
+	return b.client.Publish(b.ctx, b.channel, data).Err()
+}
+
+func (b *redisBus) PublishTo(node string, key string, data []byte) error {
+	return b.client.Publish(b.ctx, b.channel+":"+node, data).Err()
+}
+
+func (b *redisBus) Subscribe(handler Handler) error {
+	channels := []string{b.channel, b.channel + ":" + b.node}
+	pubSub := b.client.Subscribe(b.ctx, channels...)
+	// 等待每个channel的订阅确认，确保返回后发布的消息都能收到
+	for range channels {
+		if _, err := pubSub.Receive(b.ctx); err != nil {
+			_ = pubSub.Close()
+			return err
+		}
+	}
+	b.pubSubs = append(b.pubSubs, pubSub)
+
+	go func() {
+		// 总线关闭后Channel()会被关闭，协程随之退出
+		for msg := range pubSub.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return nil
+}
+
+func (b *redisBus) Close() error {
+	b.cancel()
+	for _, pubSub := range b.pubSubs {
+		_ = pubSub.Close()
+	}
+	return b.client.Close()
+}
@@ -2,6 +2,7 @@ package pool
 
 import (
 	"fmt"
+	"sync"
 
 	"chat-room/config"
 
@@ -18,7 +19,10 @@ db对象在调用它的方法时，会从数据库连接池中获取新的连接
 */
 var _db *gorm.DB
 
-func init() {
+var once sync.Once
+
+// connect 首次使用时连接数据库，不在包初始化时连接，不需要数据库的功能(如单元测试)可以直接引用依赖本包的代码
+func connect() {
 	conf := config.GetConfig()      //配置
 	username := conf.MySQL.User     //账号
 	password := conf.MySQL.Password //密码
@@ -65,5 +69,6 @@ func init() {
 
 // GetDB 获取数据库连接句柄
 func GetDB() *gorm.DB {
+	once.Do(connect)
 	return _db
 }
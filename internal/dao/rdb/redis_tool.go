@@ -0,0 +1,33 @@
+package rdb
+
+import (
+	"context"
+	"time"
+
+	"chat-room/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// NewClient
+//
+//	@Description: 根据配置创建redis客户端，并检查连接是否可用
+//	@Description: 不像mysql连接池那样在init中全局初始化，只有用到redis(消息总线、在线状态等)时才会创建
+//	@param conf
+//	@return *redis.Client
+//	@return error
+func NewClient(conf config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     conf.Addr,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
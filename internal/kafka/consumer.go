@@ -1,48 +1,100 @@
 package kafka
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"chat-room/pkg/global/log"
 	"github.com/Shopify/sarama"
-	"strings"
 )
 
-var consumer sarama.Consumer
+var consumerGroup sarama.ConsumerGroup
+var cancelConsume context.CancelFunc
 
 type ConsumerCallback func(data []byte)
 
-// 初始化消费者
-func InitConsumer(hosts string) {
+// InitConsumer
+//
+//	@Description: 初始化消费组
+//	@Description: 消费组会覆盖topic的全部分区，并在消息投递后提交offset，节点重启后从上次提交的位置继续消费
+//	@param hosts
+//	@param groupId 每个节点都持有不同的websocket连接，需要收到全部消息，因此每个节点使用各自固定的消费组
+//	@return error
+func InitConsumer(hosts, groupId string) error {
 	config := sarama.NewConfig()
-	client, err := sarama.NewClient(strings.Split(hosts, ","), config)
-	if nil != err {
-		log.Logger.Error("init kafka consumer client error", log.Any("init kafka consumer client error", err.Error()))
-	}
+	config.Version = sarama.V2_0_0_0 // 消费组需要kafka 0.10.2以上的协议版本
+	// 新建的消费组从最新的消息开始消费，已提交过offset的消费组从提交位置继续
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	config.Consumer.Return.Errors = true
 
-	consumer, err = sarama.NewConsumerFromClient(client)
+	var err error
+	consumerGroup, err = sarama.NewConsumerGroup(strings.Split(hosts, ","), groupId, config)
 	if nil != err {
-		log.Logger.Error("init kafka consumer error", log.Any("init kafka consumer error", err.Error()))
+		log.Logger.Error("init kafka consumer group error", log.Any("init kafka consumer group error", err.Error()))
+		return err
 	}
+
+	go func(group sarama.ConsumerGroup) {
+		for err := range group.Errors() {
+			log.Logger.Error("kafka consumer group error", log.Any("kafka consumer group error", err))
+		}
+	}(consumerGroup)
+	return nil
 }
 
-// 消费消息，通过回调函数进行
-func ConsumerMsg(callBack ConsumerCallback) {
-	partitionConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetNewest)
-	if nil != err {
-		log.Logger.Error("iConsumePartition error", log.Any("ConsumePartition error", err.Error()))
-		return
+// groupHandler 消费组回调，逐条投递分区中的消息
+type groupHandler struct {
+	callBack ConsumerCallback
+}
+
+func (h groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if nil != h.callBack {
+			h.callBack(msg.Value)
+		}
+		// 消息投递完成后才标记offset，由sarama定时提交，避免消息未处理就被提交而丢失
+		session.MarkMessage(msg, "")
 	}
+	return nil
+}
 
-	defer partitionConsumer.Close()
+// 消费消息，通过回调函数进行；未指定topics时消费公共topic
+func ConsumerMsg(callBack ConsumerCallback, topics ...string) {
+	if len(topics) == 0 {
+		topics = []string{topic}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelConsume = cancel
+	handler := groupHandler{callBack: callBack}
 	for {
-		msg := <-partitionConsumer.Messages()
-		if nil != callBack {
-			callBack(msg.Value)
+		// 分区重新分配时Consume会返回，需要重新加入消费组
+		if err := consumerGroup.Consume(ctx, topics, handler); err != nil {
+			if err == sarama.ErrClosedConsumerGroup {
+				return
+			}
+			log.Logger.Error("kafka consume error", log.Any("kafka consume error", err.Error()))
+			time.Sleep(time.Second)
+		}
+		if ctx.Err() != nil {
+			return
 		}
 	}
 }
 
 func CloseConsumer() {
-	if nil != consumer {
-		consumer.Close()
+	if nil != cancelConsume {
+		cancelConsume()
+	}
+	if nil != consumerGroup {
+		consumerGroup.Close()
 	}
 }
@@ -3,6 +3,7 @@ package kafka
 import (
 	"strings"
 
+	"chat-room/pkg/errors"
 	"chat-room/pkg/global/log"
 	"github.com/Shopify/sarama"
 )
@@ -14,24 +15,69 @@ var topic string = "default_message"
 //  @Description: 初始化生产者
 //  @param topicInput
 //  @param hosts
-func InitProducer(topicInput, hosts string) {
+//  @return error
+func InitProducer(topicInput, hosts string) error {
 	topic = topicInput
 	config := sarama.NewConfig()
 	config.Producer.Compression = sarama.CompressionGZIP
+	// 按消息key做哈希分区，同一会话的消息总是落在同一个分区，从而保证会话内消息有序
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	// 发送失败的消息会写入Errors()，必须有协程消费，否则生产者会被阻塞
+	config.Producer.Return.Errors = true
 	client, err := sarama.NewClient(strings.Split(hosts, ","), config)
 	if nil != err {
 		log.Logger.Error("init kafka client error", log.Any("init kafka client error", err.Error()))
+		return err
 	}
 
 	producer, err = sarama.NewAsyncProducerFromClient(client)
 	if nil != err {
 		log.Logger.Error("init kafka async client error", log.Any("init kafka async client error", err.Error()))
+		return err
+	}
+
+	go handleProducerErrors(producer)
+	return nil
+}
+
+// handleProducerErrors 异步生产者的发送结果不会返回给调用方，失败的消息统一在这里记录
+func handleProducerErrors(p sarama.AsyncProducer) {
+	for err := range p.Errors() {
+		var key string
+		if err.Msg != nil && err.Msg.Key != nil {
+			keyBytes, _ := err.Msg.Key.Encode()
+			key = string(keyBytes)
+		}
+		log.Logger.Error("kafka produce message error",
+			log.String("key", key), log.Any("kafka produce message error", err.Err))
 	}
 }
 
-func Send(data []byte) {
-	be := sarama.ByteEncoder(data)
-	producer.Input() <- &sarama.ProducerMessage{Topic: topic, Key: nil, Value: be}
+// Send
+//  @Description: 发送消息到公共topic，key相同的消息会被投递到同一分区
+//  @param key
+//  @param data
+//  @return error
+func Send(key string, data []byte) error {
+	return SendTo(topic, key, data)
+}
+
+// SendTo
+//  @Description: 发送消息到指定topic
+//  @param topicName
+//  @param key
+//  @param data
+//  @return error
+func SendTo(topicName string, key string, data []byte) error {
+	if producer == nil {
+		return errors.New("kafka生产者未初始化")
+	}
+	msg := &sarama.ProducerMessage{Topic: topicName, Value: sarama.ByteEncoder(data)}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	producer.Input() <- msg
+	return nil
 }
 
 func Close() {
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Conversation 用户的会话，每个用户的每个单聊/群聊各一条，记录已读位置以及免打扰、置顶设置
+// 未读数 = 会话的最大序号(conversation_sequences.seq) - 已读序号
+type Conversation struct {
+	ID             int32     `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"createAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	UserId         int32     `json:"userId" gorm:"uniqueIndex:idx_user_conversation;comment:'用户ID'"`
+	ConversationId string    `json:"conversationId" gorm:"type:varchar(150);uniqueIndex:idx_user_conversation;comment:'会话id'"`
+	MessageType    int16     `json:"messageType" gorm:"comment:'会话类型：1单聊，2群聊'"`
+	PeerId         int32     `json:"peerId" gorm:"comment:'单聊为对方用户ID，群聊为群ID'"`
+	ReadSeq        int64     `json:"readSeq" gorm:"not null;default:0;comment:'已读到的消息序号'"`
+	Muted          bool      `json:"muted" gorm:"not null;default:false;comment:'免打扰'"`
+	Pinned         bool      `json:"pinned" gorm:"not null;default:false;comment:'置顶'"`
+}
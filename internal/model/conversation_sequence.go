@@ -0,0 +1,8 @@
+package model
+
+// ConversationSequence 会话当前的最大消息序号以及最后一条消息，保存消息时加锁递增
+type ConversationSequence struct {
+	ConversationId string `json:"conversationId" gorm:"primarykey;type:varchar(150)"`
+	Seq            int64  `json:"seq" gorm:"not null;default:0"`
+	LastMessageId  int32  `json:"lastMessageId" gorm:"not null;default:0"`
+}
@@ -15,4 +15,5 @@ type Group struct {
 	UserId    int32                 `json:"userId" gorm:"index;comment:'群主ID'"`
 	Name      string                `json:"name" gorm:"type:varchar(150);comment:'群名称"`
 	Notice    string                `json:"notice" gorm:"type:varchar(350);comment:'群公告"`
+	AllMuted  bool                  `json:"allMuted" gorm:"not null;default:false;comment:'全员禁言，群主和管理员除外'"`
 }
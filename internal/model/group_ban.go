@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// GroupBan 群黑名单，被移出并禁止再次加入的用户
+type GroupBan struct {
+	ID         int32     `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"createAt"`
+	GroupId    int32     `json:"groupId" gorm:"uniqueIndex:idx_group_user;comment:'群组ID'"`
+	UserId     int32     `json:"userId" gorm:"uniqueIndex:idx_group_user;comment:'被禁止加入的用户ID'"`
+	OperatorId int32     `json:"operatorId" gorm:"comment:'操作者ID'"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// GroupInvite 群邀请链接，持有令牌的用户可以直接入群
+type GroupInvite struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	Token     string    `json:"token" gorm:"type:varchar(64);not null;uniqueIndex;comment:'邀请令牌'"`
+	GroupId   int32     `json:"groupId" gorm:"index;comment:'群组ID'"`
+	CreatorId int32     `json:"creatorId" gorm:"comment:'创建者ID'"`
+	ExpireAt  int64     `json:"expireAt" gorm:"not null;default:0;comment:'过期时间(毫秒)，0为永不过期'"`
+	MaxUses   int32     `json:"maxUses" gorm:"not null;default:0;comment:'最多使用次数，0为不限制'"`
+	Uses      int32     `json:"uses" gorm:"not null;default:0;comment:'已使用次数'"`
+	Revoked   bool      `json:"revoked" gorm:"not null;default:false;comment:'已撤销'"`
+}
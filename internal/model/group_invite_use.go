@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// GroupInviteUse 通过邀请链接入群的记录
+type GroupInviteUse struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	InviteId  int32     `json:"inviteId" gorm:"index;comment:'邀请链接ID'"`
+	GroupId   int32     `json:"groupId" gorm:"index;comment:'群组ID'"`
+	UserId    int32     `json:"userId" gorm:"comment:'入群的用户ID'"`
+}
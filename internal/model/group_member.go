@@ -15,4 +15,6 @@ type GroupMember struct {
 	GroupId   int32                 `json:"groupId" gorm:"index;comment:'群组ID'"`
 	Nickname  string                `json:"nickname" gorm:"type:varchar(350);comment:'昵称"`
 	Mute      int16                 `json:"mute" gorm:"comment:'是否禁言'"`
+	MuteUntil int64                 `json:"muteUntil" gorm:"not null;default:0;comment:'禁言截止时间(毫秒)，0为永久禁言'"`
+	Role      int16                 `json:"role" gorm:"not null;default:0;comment:'角色：0成员 1管理员 2群主'"`
 }
@@ -6,16 +6,26 @@ import (
 )
 
 // Message 聊天记录结构
+// 聊天记录按会话和id分页查询：单聊使用(from_user_id, to_user_id)索引，群聊使用(message_type, to_user_id)索引，
+// InnoDB的二级索引末尾隐含主键id，按id排序和游标过滤都可以走索引
 type Message struct {
-	ID          int32                 `json:"id" gorm:"primarykey"`
-	CreatedAt   time.Time             `json:"createAt"`
-	UpdatedAt   time.Time             `json:"updatedAt"`
-	DeletedAt   soft_delete.DeletedAt `json:"deletedAt"`
-	FromUserId  int32                 `json:"fromUserId" gorm:"index"`
-	ToUserId    int32                 `json:"toUserId" gorm:"index;comment:'发送给端的id，可为用户id或者群id'"`
-	Content     string                `json:"content" gorm:"type:varchar(2500)"`
-	MessageType int16                 `json:"messageType" gorm:"comment:'消息类型：1单聊，2群聊'"`
-	ContentType int16                 `json:"contentType" gorm:"comment:'消息内容类型：1文字 2.普通文件 3.图片 4.音频 5.视频 6.语音聊天 7.视频聊天'"`
-	Pic         string                `json:"pic" gorm:"type:text;comment:'缩略图"`
-	Url         string                `json:"url" gorm:"type:varchar(350);comment:'文件或者图片地址'"`
+	ID             int32                 `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time             `json:"createAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+	DeletedAt      soft_delete.DeletedAt `json:"deletedAt"`
+	FromUserId     int32                 `json:"fromUserId" gorm:"index;uniqueIndex:idx_from_client_msg;index:idx_from_to"`
+	ToUserId       int32                 `json:"toUserId" gorm:"index;index:idx_from_to;index:idx_type_to;comment:'发送给端的id，可为用户id或者群id'"`
+	Content        string                `json:"content" gorm:"type:varchar(2500)"`
+	MessageType    int16                 `json:"messageType" gorm:"index:idx_type_to,priority:1;comment:'消息类型：1单聊，2群聊'"`
+	ContentType    int16                 `json:"contentType" gorm:"comment:'消息内容类型：1文字 2.普通文件 3.图片 4.音频 5.视频 6.语音聊天 7.视频聊天 21.聊天记录 22.系统消息'"`
+	Pic            string                `json:"pic" gorm:"type:text;comment:'缩略图"`
+	Url            string                `json:"url" gorm:"type:varchar(350);comment:'文件或者图片地址'"`
+	ConversationId string                `json:"conversationId" gorm:"type:varchar(150);default:null;uniqueIndex:idx_conversation_seq;comment:'会话id，单聊为双方uuid按字典序拼接，群聊为群uuid'"`
+	Seq            int64                 `json:"seq" gorm:"uniqueIndex:idx_conversation_seq;comment:'会话内的消息序号，从1开始连续递增'"`
+	ReplyTo        int32                 `json:"replyTo" gorm:"not null;default:0;comment:'回复的消息ID'"`
+	ThreadRoot     int32                 `json:"threadRoot" gorm:"index;not null;default:0;comment:'所在话题的根消息ID，0为不属于任何话题'"`
+	EditedAt       int64                 `json:"editedAt" gorm:"not null;default:0;comment:'最后编辑时间(毫秒)，0为未编辑'"`
+	ForwardFrom    int32                 `json:"forwardFrom" gorm:"not null;default:0;comment:'转发的消息的原发送者ID，0为非转发消息'"`
+	Mentions       string                `json:"mentions" gorm:"type:varchar(1000);not null;default:'';comment:'@的成员uuid，逗号分隔，all为@所有人'"`
+	ClientMsgId    string                `json:"clientMsgId" gorm:"type:varchar(64);default:null;uniqueIndex:idx_from_client_msg;comment:'客户端生成的消息id，同一发送者唯一，历史消息为null'"`
 }
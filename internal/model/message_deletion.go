@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// MessageDeletion 用户删除的消息，仅对该用户隐藏，其他人仍然可见
+type MessageDeletion struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	UserId    int32     `json:"userId" gorm:"uniqueIndex:idx_user_message;comment:'用户ID'"`
+	MessageId int32     `json:"messageId" gorm:"uniqueIndex:idx_user_message;comment:'消息ID'"`
+}
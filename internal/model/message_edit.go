@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// MessageEdit 消息的编辑历史，每次编辑保存编辑前的内容
+type MessageEdit struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	MessageId int32     `json:"messageId" gorm:"index;comment:'消息ID'"`
+	Content   string    `json:"content" gorm:"type:varchar(2500);comment:'编辑前的内容'"`
+}
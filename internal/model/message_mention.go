@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MessageMention 群聊消息中被@的成员，@所有人时为除发送者外的每个成员各记录一条
+// 冗余会话id和消息序号，按会话的已读位置统计未读的@消息
+type MessageMention struct {
+	ID             int32     `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"createAt"`
+	MessageId      int32     `json:"messageId" gorm:"uniqueIndex:idx_user_message,priority:2;comment:'消息ID'"`
+	UserId         int32     `json:"userId" gorm:"uniqueIndex:idx_user_message,priority:1;index:idx_user_conversation_seq,priority:1;comment:'被@的用户ID'"`
+	ConversationId string    `json:"conversationId" gorm:"type:varchar(150);index:idx_user_conversation_seq,priority:2;comment:'会话id'"`
+	Seq            int64     `json:"seq" gorm:"index:idx_user_conversation_seq,priority:3;comment:'消息在会话内的序号'"`
+}
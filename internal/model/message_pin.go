@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// MessagePin 会话中置顶的消息，置顶对会话中的所有成员可见
+type MessagePin struct {
+	ID             int32     `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"createAt"`
+	ConversationId string    `json:"conversationId" gorm:"type:varchar(150);uniqueIndex:idx_conversation_message;comment:'会话id'"`
+	MessageId      int32     `json:"messageId" gorm:"uniqueIndex:idx_conversation_message;comment:'消息ID'"`
+	UserId         int32     `json:"userId" gorm:"comment:'置顶操作者ID'"`
+}
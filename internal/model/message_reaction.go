@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// MessageReaction 消息的表情回应，同一用户对同一条消息的同一个表情只记录一次
+type MessageReaction struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	MessageId int32     `json:"messageId" gorm:"uniqueIndex:idx_message_user_emoji;comment:'消息ID'"`
+	UserId    int32     `json:"userId" gorm:"uniqueIndex:idx_message_user_emoji;comment:'用户ID'"`
+	Emoji     string    `json:"emoji" gorm:"type:varchar(32);uniqueIndex:idx_message_user_emoji;comment:'表情'"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MessageReceipt 消息回执，记录每个接收人(群聊为每个群成员)的送达和已读时间
+type MessageReceipt struct {
+	ID          int32     `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time `json:"createAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	MessageId   int32     `json:"messageId" gorm:"uniqueIndex:idx_message_user;comment:'消息ID'"`
+	UserId      int32     `json:"userId" gorm:"uniqueIndex:idx_message_user;comment:'接收人ID'"`
+	DeliveredAt int64     `json:"deliveredAt" gorm:"comment:'送达时间(毫秒)，0为未送达'"`
+	ReadAt      int64     `json:"readAt" gorm:"comment:'已读时间(毫秒)，0为未读'"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// MessageRecord 合并转发的聊天记录中包含的原消息，按id顺序即为记录中的顺序
+type MessageRecord struct {
+	ID        int32     `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createAt"`
+	RecordId  int32     `json:"recordId" gorm:"uniqueIndex:idx_record_message;comment:'聊天记录消息ID'"`
+	MessageId int32     `json:"messageId" gorm:"uniqueIndex:idx_record_message;index;comment:'原消息ID'"`
+}
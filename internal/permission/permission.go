@@ -0,0 +1,93 @@
+package permission
+
+import (
+	"time"
+
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+)
+
+// 群内的操作，权限矩阵见groupPermissions
+const (
+	GROUP_ACTION_RENAME   = "rename"   // 修改群名称
+	GROUP_ACTION_NOTICE   = "notice"   // 修改群公告
+	GROUP_ACTION_INVITE   = "invite"   // 邀请成员入群
+	GROUP_ACTION_LINK     = "link"     // 管理邀请链接及查看入群记录
+	GROUP_ACTION_KICK     = "kick"     // 移出成员
+	GROUP_ACTION_MUTE     = "mute"     // 禁言成员
+	GROUP_ACTION_PIN      = "pin"      // 置顶消息
+	GROUP_ACTION_PROMOTE  = "promote"  // 设置、取消管理员
+	GROUP_ACTION_TRANSFER = "transfer" // 转让群主
+	GROUP_ACTION_DISSOLVE = "dissolve" // 解散群聊
+)
+
+// groupPermissions 执行各项操作需要的最低角色
+var groupPermissions = map[string]int16{
+	GROUP_ACTION_RENAME:   constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_NOTICE:   constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_INVITE:   constant.GROUP_ROLE_MEMBER,
+	GROUP_ACTION_LINK:     constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_KICK:     constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_MUTE:     constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_PIN:      constant.GROUP_ROLE_ADMIN,
+	GROUP_ACTION_PROMOTE:  constant.GROUP_ROLE_OWNER,
+	GROUP_ACTION_TRANSFER: constant.GROUP_ROLE_OWNER,
+	GROUP_ACTION_DISSOLVE: constant.GROUP_ROLE_OWNER,
+}
+
+// Role
+//
+//	@Description: 成员在群中的角色，群主以groups.user_id为准：功能上线前创建的群没有在成员表中记录群主角色，
+//	@Description: 其他成员残留的群主角色也不生效
+//	@param group
+//	@param member
+//	@return int16
+func Role(group *model.Group, member *model.GroupMember) int16 {
+	if group.UserId == member.UserId {
+		return constant.GROUP_ROLE_OWNER
+	}
+	if member.Role >= constant.GROUP_ROLE_OWNER {
+		return constant.GROUP_ROLE_MEMBER
+	}
+	return member.Role
+}
+
+// Check 校验该角色是否可以在群中执行该操作，未知的操作一律拒绝
+func Check(role int16, action string) error {
+	required, ok := groupPermissions[action]
+	if !ok || role < required {
+		return errors.New("没有权限执行该操作")
+	}
+	return nil
+}
+
+// CheckManage 校验是否可以对另一个成员执行操作(移出、禁言等)，只能管理角色比自己低的成员
+func CheckManage(role int16, targetRole int16) error {
+	if targetRole >= role {
+		return errors.New("不能对同级或更高角色的成员执行该操作")
+	}
+	return nil
+}
+
+// Muted 成员是否处于禁言中，MuteUntil为0表示永久禁言
+func Muted(member *model.GroupMember, now time.Time) bool {
+	return member.Mute == 1 && (member.MuteUntil == 0 || member.MuteUntil > now.UnixMilli())
+}
+
+// CheckSend
+//
+//	@Description: 校验成员是否可以在群中发言：被禁言的成员不能发言，全员禁言时只有群主和管理员可以发言
+//	@param group
+//	@param member
+//	@param now
+//	@return error
+func CheckSend(group *model.Group, member *model.GroupMember, now time.Time) error {
+	if Muted(member, now) {
+		return errors.New("你已被禁言")
+	}
+	if group.AllMuted && Role(group, member) < constant.GROUP_ROLE_ADMIN {
+		return errors.New("全员禁言中，只有群主和管理员可以发言")
+	}
+	return nil
+}
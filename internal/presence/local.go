@@ -0,0 +1,90 @@
+package presence
+
+import (
+	"sync"
+	"time"
+)
+
+// localRegistry
+// @Description: 单机部署时的在线状态注册表，只记录本节点的在线用户
+type localRegistry struct {
+	node      string
+	mutex     sync.RWMutex
+	users     map[string]struct{}
+	away      map[string]struct{}
+	lastSeen  map[string]int64
+	announced map[string]string
+}
+
+// NewLocalRegistry 创建单机在线状态注册表
+func NewLocalRegistry(node string) Registry {
+	return &localRegistry{
+		node:      node,
+		users:     make(map[string]struct{}),
+		away:      make(map[string]struct{}),
+		lastSeen:  make(map[string]int64),
+		announced: make(map[string]string),
+	}
+}
+
+func (r *localRegistry) Online(userUuid string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.users[userUuid] = struct{}{}
+	delete(r.away, userUuid)
+	return nil
+}
+
+func (r *localRegistry) Offline(userUuid string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.users, userUuid)
+	delete(r.away, userUuid)
+	r.lastSeen[userUuid] = time.Now().UnixMilli()
+	return nil
+}
+
+func (r *localRegistry) Nodes(userUuid string) ([]string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if _, ok := r.users[userUuid]; ok {
+		return []string{r.node}, nil
+	}
+	return nil, nil
+}
+
+func (r *localRegistry) SetAway(userUuid string, away bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.users[userUuid]; away && ok {
+		r.away[userUuid] = struct{}{}
+	} else {
+		delete(r.away, userUuid)
+	}
+	return nil
+}
+
+func (r *localRegistry) Status(userUuid string) (Status, error) {
+	nodes, _ := r.Nodes(userUuid)
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, away := r.away[userUuid]
+	return newStatus(nodes, away, r.lastSeen[userUuid]), nil
+}
+
+func (r *localRegistry) Announce(userUuid string, status string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.announced[userUuid] == status {
+		return false, nil
+	}
+	r.announced[userUuid] = status
+	return true, nil
+}
+
+// OnChange 单机部署没有其他节点，不会发生
+func (r *localRegistry) OnChange(func(userUuid string)) {}
+
+func (r *localRegistry) Close() error {
+	return nil
+}
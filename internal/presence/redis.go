@@ -0,0 +1,269 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	userKeyPrefix  = "presence:user:"      // hash 节点id -> 上线时间，记录用户连接在哪些节点上
+	nodeKeyPrefix  = "presence:node:"      // 节点存活标记，节点定时续期，宕机后自动过期
+	nodeUsersKey   = ":users"              // set 节点上的在线用户，节点重启或退出时用于清理在线记录
+	nodesKey       = "presence:nodes"      // set 全部节点，用于发现已宕机的节点
+	awayKeyPrefix  = "presence:away:"      // 用户离开标记
+	seenKeyPrefix  = "presence:seen:"      // 用户最后在线时间(毫秒)
+	announcePrefix = "presence:announced:" // 最近一次推送给好友的状态
+	defaultNodeTTL = 30 * time.Second
+)
+
+// redisRegistry
+// @Description: 基于redis的在线状态注册表，多个节点共享
+// @Description: 节点宕机后存活标记过期，其他节点定时清除该节点的在线记录、记录最后在线时间并通知状态变化，
+// @Description: 查询时发现的已宕机节点也会顺带清除
+type redisRegistry struct {
+	client   *redis.Client
+	node     string
+	ttl      time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mutex    sync.RWMutex
+	onChange func(userUuid string)
+}
+
+// NewRedisRegistry
+//
+//	@Description: 创建redis在线状态注册表，并开始定时为本节点续期
+//	@param client
+//	@param node 本节点标识
+//	@param ttl 节点存活标记的有效期
+//	@return Registry
+//	@return error
+func NewRedisRegistry(client *redis.Client, node string, ttl time.Duration) (Registry, error) {
+	if ttl <= 0 {
+		ttl = defaultNodeTTL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &redisRegistry{
+		client: client,
+		node:   node,
+		ttl:    ttl,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	// 节点宕机重启后，上次运行留下的在线记录已经失效，需要先清除
+	if err := r.clear(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := r.keepAlive(); err != nil {
+		cancel()
+		return nil, err
+	}
+	r.sweep()
+	go r.heartbeat()
+	return r, nil
+}
+
+func (r *redisRegistry) Online(userUuid string) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(r.ctx, userKeyPrefix+userUuid, r.node, time.Now().Unix())
+		pipe.SAdd(r.ctx, nodeKeyPrefix+r.node+nodeUsersKey, userUuid)
+		pipe.Del(r.ctx, awayKeyPrefix+userUuid)
+		return nil
+	})
+	return err
+}
+
+func (r *redisRegistry) Offline(userUuid string) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(r.ctx, userKeyPrefix+userUuid, r.node)
+		pipe.SRem(r.ctx, nodeKeyPrefix+r.node+nodeUsersKey, userUuid)
+		pipe.Set(r.ctx, seenKeyPrefix+userUuid, time.Now().UnixMilli(), 0)
+		return nil
+	})
+	return err
+}
+
+func (r *redisRegistry) Nodes(userUuid string) ([]string, error) {
+	nodes, err := r.client.HKeys(r.ctx, userKeyPrefix+userUuid).Result()
+	if err != nil || len(nodes) == 0 {
+		return nil, err
+	}
+
+	// 批量检查节点是否存活
+	pipe := r.client.Pipeline()
+	alive := make([]*redis.IntCmd, len(nodes))
+	for i, node := range nodes {
+		alive[i] = pipe.Exists(r.ctx, nodeKeyPrefix+node)
+	}
+	if _, err = pipe.Exec(r.ctx); err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for i, node := range nodes {
+		if node == r.node || alive[i].Val() > 0 {
+			result = append(result, node)
+			continue
+		}
+		// 节点已宕机，清除它留下的在线记录并记录最后在线时间
+		_, _ = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(r.ctx, userKeyPrefix+userUuid, node)
+			pipe.Set(r.ctx, seenKeyPrefix+userUuid, time.Now().UnixMilli(), 0)
+			return nil
+		})
+		r.changed(userUuid)
+	}
+	return result, nil
+}
+
+func (r *redisRegistry) SetAway(userUuid string, away bool) error {
+	if away {
+		return r.client.Set(r.ctx, awayKeyPrefix+userUuid, 1, 0).Err()
+	}
+	return r.client.Del(r.ctx, awayKeyPrefix+userUuid).Err()
+}
+
+func (r *redisRegistry) Status(userUuid string) (Status, error) {
+	nodes, err := r.Nodes(userUuid)
+	if err != nil {
+		return Status{}, err
+	}
+	pipe := r.client.Pipeline()
+	away := pipe.Exists(r.ctx, awayKeyPrefix+userUuid)
+	seen := pipe.Get(r.ctx, seenKeyPrefix+userUuid)
+	if _, err = pipe.Exec(r.ctx); err != nil && err != redis.Nil {
+		return Status{}, err
+	}
+	lastSeen, _ := seen.Int64()
+	return newStatus(nodes, away.Val() > 0, lastSeen), nil
+}
+
+// Announce 使用GETSET原子地替换状态，多个节点同时推送同一次变化时只有一个节点会得到旧状态
+func (r *redisRegistry) Announce(userUuid string, status string) (bool, error) {
+	previous, err := r.client.GetSet(r.ctx, announcePrefix+userUuid, status).Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+	return previous != status, nil
+}
+
+func (r *redisRegistry) OnChange(handler func(userUuid string)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onChange = handler
+}
+
+// changed 其他节点宕机导致用户的在线状态发生了变化
+func (r *redisRegistry) changed(userUuid string) {
+	r.mutex.RLock()
+	handler := r.onChange
+	r.mutex.RUnlock()
+	if handler != nil {
+		handler(userUuid)
+	}
+}
+
+func (r *redisRegistry) Close() error {
+	r.cancel()
+	_ = r.clear()
+	return r.client.Close()
+}
+
+// keepAlive 为本节点的存活标记续期
+func (r *redisRegistry) keepAlive() error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(r.ctx, nodeKeyPrefix+r.node, time.Now().Unix(), r.ttl)
+		pipe.SAdd(r.ctx, nodesKey, r.node)
+		return nil
+	})
+	return err
+}
+
+// sweep
+//
+//	@Description: 清除已宕机节点留下的在线记录：记录其上用户的最后在线时间，并通知状态变化
+//	@Description: 多个节点同时发现时，只有从节点集合中移除成功的节点负责清除
+//	@receiver r
+func (r *redisRegistry) sweep() {
+	nodes, err := r.client.SMembers(r.ctx, nodesKey).Result()
+	if err != nil {
+		return
+	}
+	for _, node := range nodes {
+		if node == r.node {
+			continue
+		}
+		if alive, err := r.client.Exists(r.ctx, nodeKeyPrefix+node).Result(); err != nil || alive > 0 {
+			continue
+		}
+		if removed, err := r.client.SRem(r.ctx, nodesKey, node).Result(); err != nil || removed == 0 {
+			continue
+		}
+		r.expire(node)
+	}
+}
+
+// expire 清除已宕机节点上全部用户的在线记录
+func (r *redisRegistry) expire(node string) {
+	usersKey := nodeKeyPrefix + node + nodeUsersKey
+	users, err := r.client.SMembers(r.ctx, usersKey).Result()
+	if err != nil {
+		return
+	}
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		now := time.Now().UnixMilli()
+		for _, user := range users {
+			pipe.HDel(r.ctx, userKeyPrefix+user, node)
+			pipe.Set(r.ctx, seenKeyPrefix+user, now, 0)
+		}
+		pipe.Del(r.ctx, usersKey)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	for _, user := range users {
+		r.changed(user)
+	}
+}
+
+// heartbeat 定时续期，续期间隔为有效期的三分之一，允许偶尔失败
+func (r *redisRegistry) heartbeat() {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = r.keepAlive()
+			r.sweep()
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// clear 清除本节点的全部在线记录以及存活标记
+func (r *redisRegistry) clear() error {
+	ctx := context.Background()
+	usersKey := nodeKeyPrefix + r.node + nodeUsersKey
+	users, err := r.client.SMembers(ctx, usersKey).Result()
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		now := time.Now().UnixMilli()
+		for _, user := range users {
+			pipe.HDel(ctx, userKeyPrefix+user, r.node)
+			pipe.Set(ctx, seenKeyPrefix+user, now, 0)
+		}
+		pipe.Del(ctx, usersKey, nodeKeyPrefix+r.node)
+		pipe.SRem(ctx, nodesKey, r.node)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,78 @@
+package presence
+
+import (
+	"time"
+
+	"chat-room/config"
+	"chat-room/internal/dao/rdb"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+)
+
+// Registry
+// @Description: 在线状态注册表，记录每个用户的设备连接在哪些节点上
+// @Description: 分布式部署时据此将单聊消息只投递给持有接收人连接的节点
+type Registry interface {
+	// Online 用户在本节点的首个设备上线
+	Online(userUuid string) error
+	// Offline 用户在本节点的设备全部下线
+	Offline(userUuid string) error
+	// Nodes 持有该用户连接的存活节点，为空说明用户不在线
+	Nodes(userUuid string) ([]string, error)
+	// SetAway 标记用户是否处于离开状态(在线但长时间无操作)
+	SetAway(userUuid string, away bool) error
+	// Status 用户当前的在线状态
+	Status(userUuid string) (Status, error)
+	// Announce 记录最近一次推送给好友的状态，返回与上次推送相比是否发生了变化
+	// 多节点部署时同一次状态变化可能被多个节点察觉，由注册表保证只有一个节点推送
+	Announce(userUuid string, status string) (bool, error)
+	// OnChange 设置回调，其他节点宕机、其上用户的在线记录被清除时调用，用于推送状态变化
+	OnChange(handler func(userUuid string))
+	// Close 关闭注册表，清除本节点的在线记录
+	Close() error
+}
+
+// Status 用户的在线状态
+type Status struct {
+	Status   string // online/away/offline
+	LastSeen int64  // 最后在线时间(毫秒)，用户从未下线过时为0
+}
+
+// newStatus 根据用户所在节点、离开标记和最后在线时间得出在线状态
+func newStatus(nodes []string, away bool, lastSeen int64) Status {
+	switch {
+	case len(nodes) == 0:
+		return Status{Status: constant.PRESENCE_OFFLINE, LastSeen: lastSeen}
+	case away:
+		return Status{Status: constant.PRESENCE_AWAY, LastSeen: lastSeen}
+	default:
+		return Status{Status: constant.PRESENCE_ONLINE, LastSeen: lastSeen}
+	}
+}
+
+// New
+//
+//	@Description: 根据配置创建在线状态注册表，单机部署使用local，多节点部署需使用redis
+//	@Description: 使用kafka、redis等多节点消息队列时不能使用local，直接返回错误，服务不启动
+//	@param conf
+//	@return Registry
+//	@return error
+func New(conf config.TomlConfig) (Registry, error) {
+	switch conf.Presence.Registry {
+	case constant.LOCAL, "":
+		// local只知道本节点上的用户，多节点总线下单聊消息会因此只投递到本节点，接收人在其他节点上时收不到
+		if channel := conf.MsgChannelType.ChannelType; channel != constant.GO_CHANNEL && channel != "" {
+			return nil, errors.New("消息队列类型为" + channel + "时为多节点部署，在线状态注册表需配置为redis")
+		}
+		return NewLocalRegistry(conf.NodeId), nil
+	case constant.REDIS:
+		client, err := rdb.NewClient(conf.Redis)
+		if err != nil {
+			return nil, err
+		}
+		ttl := time.Duration(conf.Presence.NodeTTL) * time.Second
+		return NewRedisRegistry(client, conf.NodeId, ttl)
+	default:
+		return nil, errors.New("不支持的在线状态注册表类型: " + conf.Presence.Registry)
+	}
+}
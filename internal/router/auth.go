@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+const bearerPrefix = "Bearer "
+
+// Auth
+//
+//	@Description: 鉴权中间件，校验accessToken并将当前登录用户的uuid写入上下文
+//	@Description: 浏览器的WebSocket无法自定义请求头，因此也支持通过 ?token= 参数传递
+//	@return gin.HandlerFunc
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := c.Query("token")
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, bearerPrefix) {
+			tokenStr = strings.TrimPrefix(header, bearerPrefix)
+		}
+		if tokenStr == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, "请先登录"))
+			return
+		}
+
+		claims, err := token.ParseToken(tokenStr, constant.ACCESS_TOKEN)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, err.Error()))
+			return
+		}
+
+		c.Set(constant.CTX_USER_UUID, claims.Uuid)
+		c.Next()
+	}
+}
@@ -33,42 +33,87 @@ func NewRouter() *gin.Engine {
 	//server.Use(gin.Recovery())
 
 	socket := RunSocket
+	auth := Auth()
+
+	// 无需登录即可访问的路由
+	publicGroup := server.Group("")
+	{
+		publicGroup.POST("/user/register", v1.Register)    // 注册
+		publicGroup.POST("/user/login", v1.Login)          // 登录
+		publicGroup.POST("/user/refresh", v1.RefreshToken) // 刷新token
+		publicGroup.GET("/file/:fileName", v1.GetFile)     // 头像等静态文件通过img标签直接获取，无法携带token
+	}
 
 	// 用户路由组
-	userGroup := server.Group("/user")
+	userGroup := server.Group("/user", auth)
 	{
 		userGroup.GET("", v1.GetUserList)
 		userGroup.GET("/:uuid", v1.GetUserDetails)
 		userGroup.PUT("", v1.ModifyUserInfo)
 		userGroup.GET("/name", v1.GetUserOrGroupByName)
-		userGroup.POST("/register", v1.Register) // 注册
-		userGroup.POST("/login", v1.Login)       // 登录
+		userGroup.GET("/presence", v1.GetPresence) // 批量查询在线状态及最后在线时间
 	}
 
 	// 聊天群路由组
-	chatGroup := server.Group("/group")
+	chatGroup := server.Group("/group", auth)
 	{
 		chatGroup.GET("/:uuid", v1.GetGroup)
 		chatGroup.POST("/:uuid", v1.SaveGroup)                     // 创建群聊
-		chatGroup.POST("/join/:userUuid/:groupUuid", v1.JoinGroup) // 加入群聊
+		chatGroup.POST("/join/:userUuid/:groupUuid", v1.JoinGroup) // 加入群聊，需要开启[group] openJoin
 		chatGroup.GET("/user/:uuid", v1.GetGroupUsers)
+		chatGroup.PUT("/info/:groupUuid", v1.ModifyGroup)               // 修改群名称、群公告
+		chatGroup.POST("/invite/:groupUuid/:userUuid", v1.InviteMember) // 邀请入群
+		chatGroup.POST("/admin/:groupUuid/:userUuid", v1.AddAdmin)      // 设置管理员
+		chatGroup.DELETE("/admin/:groupUuid/:userUuid", v1.RemoveAdmin) // 取消管理员
+		chatGroup.PUT("/mute/:groupUuid/:userUuid", v1.MuteMember)      // 禁言成员
+		chatGroup.DELETE("/mute/:groupUuid/:userUuid", v1.UnmuteMember) // 解除禁言
+		chatGroup.PUT("/allmute/:groupUuid", v1.SetAllMuted)            // 全员禁言
+		chatGroup.POST("/leave/:groupUuid", v1.LeaveGroup)              // 退出群聊
+		chatGroup.DELETE("/member/:groupUuid/:userUuid", v1.KickMember) // 移出成员
+		chatGroup.POST("/ban/:groupUuid/:userUuid", v1.BanMember)       // 移出成员并禁止再次加入
+		chatGroup.DELETE("/ban/:groupUuid/:userUuid", v1.UnbanMember)
+		chatGroup.GET("/ban/:groupUuid", v1.GetBans)                   // 群黑名单
+		chatGroup.PUT("/owner/:groupUuid/:userUuid", v1.TransferOwner) // 转让群主
+		chatGroup.DELETE("/:groupUuid", v1.DissolveGroup)              // 解散群聊
+		chatGroup.POST("/link/:groupUuid", v1.CreateInvite)            // 创建邀请链接
+		chatGroup.GET("/link/:groupUuid", v1.GetInvites)
+		chatGroup.DELETE("/link/:groupUuid/:token", v1.RevokeInvite) // 撤销邀请链接
+		chatGroup.GET("/link/:groupUuid/uses", v1.GetInviteUses)     // 通过邀请链接入群的记录
+		chatGroup.POST("/redeem/:token", v1.RedeemInvite)            // 通过邀请链接入群
 		// 更换群头像 todo
 	}
 
 	// 文件路由组
-	fileGroup := server.Group("file")
+	fileGroup := server.Group("file", auth)
 	{
 		fileGroup.POST("", v1.SaveFile)
-		fileGroup.GET("/:fileName", v1.GetFile)
 	}
 
-	group1 := server.Group("")
+	group1 := server.Group("", auth)
 	{
 		group1.POST("/friend", v1.AddFriend)
 
 		group1.GET("/message", v1.GetMessage)
+		group1.POST("/message/sync", v1.SyncMessage) // 离线消息同步
+		group1.POST("/message/:id/recall", v1.RecallMessage)
+		group1.DELETE("/message/:id", v1.DeleteMessage) // 仅对自己删除
+		group1.PUT("/message/:id", v1.EditMessage)
+		group1.GET("/message/:id/edits", v1.GetEditHistory)
+		group1.GET("/message/thread/:id", v1.GetThread) // 话题及其回复
+		group1.GET("/message/mentions", v1.GetMentions) // @我的消息
+		group1.GET("/message/search", v1.SearchMessage)
+		group1.POST("/message/forward", v1.ForwardMessage)
+		group1.GET("/message/:id/records", v1.GetChatRecords) // 展开合并转发的聊天记录
+		group1.POST("/message/:id/reactions", v1.AddReaction)
+		group1.DELETE("/message/:id/reactions/:emoji", v1.RemoveReaction)
+		group1.POST("/message/:id/pin", v1.PinMessage)
+		group1.DELETE("/message/:id/pin", v1.UnpinMessage)
+		group1.GET("/pins", v1.GetPins) // 会话中置顶的消息
+
+		group1.GET("/conversations", v1.GetConversations)
+		group1.PUT("/conversations/:conversationId", v1.ModifyConversation) // 免打扰、置顶
 
-		group1.GET("/socket.io", socket)
+		group1.GET("/socket.io", socket) // 握手阶段校验token，校验失败不升级为websocket
 	}
 	return server
 }
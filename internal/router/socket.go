@@ -2,10 +2,12 @@ package router
 
 import (
 	"chat-room/internal/server"
+	"chat-room/pkg/common/constant"
 	"chat-room/pkg/global/log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -19,10 +21,8 @@ var upGrader = websocket.Upgrader{
 
 // RunSocket 每来一个用户，创建一个socket连接
 func RunSocket(c *gin.Context) {
-	user := c.Query("user")
-	if user == "" {
-		return
-	}
+	// 连接归属于token中的登录用户，不再信任客户端传入的 ?user= 参数
+	user := c.GetString(constant.CTX_USER_UUID)
 	log.Logger.Info("newUser", zap.String("newUser", user))
 	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil) // 将http协议升级为websocket协议
 	if err != nil {
@@ -31,6 +31,7 @@ func RunSocket(c *gin.Context) {
 
 	client := &server.Client{
 		Name: user,
+		Id:   uuid.NewString(),
 		Conn: ws,
 		Send: make(chan []byte),
 	}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// 高亮摘要中关键字前后保留的字数
+const highlightContext = 20
+
+// Highlight
+//
+//	@Description: 生成高亮摘要：截取第一次命中关键字前后的若干个字，命中的关键字用<em>标记，其余内容做HTML转义
+//	@Description: 关键字匹配不区分大小写，没有命中时返回开头的一段内容
+//	@param content
+//	@param keyword
+//	@return string
+func Highlight(content string, keyword string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	key := []rune(strings.ToLower(keyword))
+	// 个别字符转小写后长度会变化，此时无法按位置对应，只做整体截取
+	if len(key) == 0 || len(lower) != len(runes) {
+		return html.EscapeString(truncate(runes, 2*highlightContext))
+	}
+
+	first := indexRunes(lower, key, 0)
+	if first < 0 {
+		return html.EscapeString(truncate(runes, 2*highlightContext))
+	}
+	start := first - highlightContext
+	if start < 0 {
+		start = 0
+	}
+	end := first + len(key) + highlightContext
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("...")
+	}
+	for pos := start; pos < end; {
+		hit := indexRunes(lower[:end], key, pos)
+		if hit < 0 {
+			builder.WriteString(html.EscapeString(string(runes[pos:end])))
+			break
+		}
+		builder.WriteString(html.EscapeString(string(runes[pos:hit])))
+		builder.WriteString("<em>" + html.EscapeString(string(runes[hit:hit+len(key)])) + "</em>")
+		pos = hit + len(key)
+	}
+	if end < len(runes) {
+		builder.WriteString("...")
+	}
+	return builder.String()
+}
+
+// indexRunes key在text中从from开始第一次出现的位置，没有时返回-1
+func indexRunes(text []rune, key []rune, from int) int {
+	for i := from; i+len(key) <= len(text); i++ {
+		if string(text[i:i+len(key)]) == string(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// truncate 截取前n个字
+func truncate(runes []rune, n int) string {
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "..."
+}
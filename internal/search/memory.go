@@ -0,0 +1,160 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 启动时每批从数据库加载的消息条数
+const loadBatchSize = 1000
+
+// MemoryEngine
+// @Description: 进程内的倒排索引，以单个字和相邻两个字为词项，检索时取关键字中最少命中的词项求交，再逐条校验
+// @Description: 索引只保存在本节点内存中，多节点部署时需使用mysql引擎
+type MemoryEngine struct {
+	mutex    sync.RWMutex
+	docs     map[int32]*Document       // 消息id -> 消息，content为小写
+	postings map[string]map[int32]bool // 词项 -> 包含该词项的消息id
+}
+
+// NewMemoryEngine 创建空的进程内索引
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		docs:     make(map[int32]*Document),
+		postings: make(map[string]map[int32]bool),
+	}
+}
+
+// Load 从数据库分批加载未撤回的消息建立索引，消息表还不存在时跳过
+func (e *MemoryEngine) Load(db *gorm.DB) error {
+	if !db.Migrator().HasTable("messages") {
+		return nil
+	}
+	var lastId int32
+	for {
+		var docs []Document
+		err := db.Table("messages").Select("id, message_type, from_user_id, to_user_id, content_type, content, created_at").
+			Where("deleted_at = 0 AND content <> '' AND id > ?", lastId).Order("id").Limit(loadBatchSize).Scan(&docs).Error
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			_ = e.Index(doc)
+		}
+		if len(docs) < loadBatchSize {
+			return nil
+		}
+		lastId = docs[len(docs)-1].ID
+	}
+}
+
+func (e *MemoryEngine) Index(doc Document) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.remove(doc.ID)
+	if doc.Content == "" {
+		return nil
+	}
+	doc.Content = strings.ToLower(doc.Content)
+	e.docs[doc.ID] = &doc
+	for _, term := range indexTerms(doc.Content) {
+		ids, ok := e.postings[term]
+		if !ok {
+			ids = make(map[int32]bool)
+			e.postings[term] = ids
+		}
+		ids[doc.ID] = true
+	}
+	return nil
+}
+
+func (e *MemoryEngine) Remove(id int32) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.remove(id)
+	return nil
+}
+
+// remove 删除消息及其词项，调用方需持有写锁
+func (e *MemoryEngine) remove(id int32) {
+	doc, ok := e.docs[id]
+	if !ok {
+		return
+	}
+	delete(e.docs, id)
+	for _, term := range indexTerms(doc.Content) {
+		delete(e.postings[term], id)
+		if len(e.postings[term]) == 0 {
+			delete(e.postings, term)
+		}
+	}
+}
+
+func (e *MemoryEngine) Search(query Query) ([]int32, bool, error) {
+	keyword := strings.ToLower(query.Keyword)
+	keywordTerms := terms(keyword)
+	if len(keywordTerms) == 0 {
+		return nil, false, nil
+	}
+
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	// 候选集取命中最少的词项，其余条件逐条校验
+	candidates := e.postings[keywordTerms[0]]
+	for _, term := range keywordTerms[1:] {
+		if len(e.postings[term]) < len(candidates) {
+			candidates = e.postings[term]
+		}
+	}
+
+	ids := make([]int32, 0)
+	for id := range candidates {
+		doc := e.docs[id]
+		if strings.Contains(doc.Content, keyword) && query.matches(doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	if len(ids) > query.Size {
+		return ids[:query.Size], true, nil
+	}
+	return ids, false, nil
+}
+
+// indexTerms 消息建立索引的词项：每个字以及相邻的两个字，单个字的关键字也能检索
+func indexTerms(text string) []string {
+	result := terms(text)
+	seen := make(map[string]bool)
+	for _, r := range text {
+		term := string(r)
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// terms 关键字的词项：超过一个字时为全部相邻的两个字，否则为单个字，去重
+func terms(text string) []string {
+	runes := []rune(text)
+	switch len(runes) {
+	case 0:
+		return nil
+	case 1:
+		return []string{text}
+	}
+	var result []string
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(runes); i++ {
+		term := string(runes[i : i+2])
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+	return result
+}
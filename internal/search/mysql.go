@@ -0,0 +1,104 @@
+package search
+
+import (
+	"strings"
+
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+
+	"gorm.io/gorm"
+)
+
+// 中文没有空格分词，使用ngram解析器按相邻的两个字建立索引
+const fulltextIndex = "idx_content_fulltext"
+
+// ngram解析器默认的分词长度，更短的关键字无法使用全文索引
+const ngramTokenSize = 2
+
+type mysqlEngine struct {
+	db *gorm.DB
+}
+
+// NewMySQLEngine 使用messages表上的FULLTEXT索引检索，需要MySQL 5.7.6及以上版本
+// 索引不存在时在启动阶段创建，创建失败返回错误，不在检索请求中建表建索引
+func NewMySQLEngine(db *gorm.DB) (Engine, error) {
+	if err := createIndex(db); err != nil {
+		return nil, err
+	}
+	return &mysqlEngine{db: db}, nil
+}
+
+// Index 索引由MySQL在写入消息时维护
+func (e *mysqlEngine) Index(Document) error {
+	return nil
+}
+
+// Remove 撤回的消息已软删除，检索时排除
+func (e *mysqlEngine) Remove(int32) error {
+	return nil
+}
+
+func (e *mysqlEngine) Search(query Query) ([]int32, bool, error) {
+	db := e.db.Table("messages").Select("id").Where("deleted_at = 0")
+	if len([]rune(query.Keyword)) < ngramTokenSize {
+		db = db.Where("content LIKE ?", "%"+escapeLike(query.Keyword)+"%")
+	} else {
+		// 布尔模式下的短语检索，要求关键字的ngram按顺序连续出现
+		db = db.Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", `"`+strings.ReplaceAll(query.Keyword, `"`, " ")+`"`)
+	}
+
+	scope := e.db.Where("message_type = ? AND to_user_id IN ?", constant.MESSAGE_TYPE_GROUP, query.GroupIds)
+	if query.Direct {
+		if query.PeerId > 0 {
+			scope = scope.Or("message_type = ? AND ((from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?))",
+				constant.MESSAGE_TYPE_USER, query.UserId, query.PeerId, query.PeerId, query.UserId)
+		} else {
+			scope = scope.Or("message_type = ? AND (from_user_id = ? OR to_user_id = ?)",
+				constant.MESSAGE_TYPE_USER, query.UserId, query.UserId)
+		}
+	}
+	db = db.Where(scope)
+
+	if query.FromUserId > 0 {
+		db = db.Where("from_user_id = ?", query.FromUserId)
+	}
+	if query.ContentType > 0 {
+		db = db.Where("content_type = ?", query.ContentType)
+	}
+	if !query.Start.IsZero() {
+		db = db.Where("created_at >= ?", query.Start)
+	}
+	if !query.End.IsZero() {
+		db = db.Where("created_at < ?", query.End)
+	}
+	if query.Before > 0 {
+		db = db.Where("id < ?", query.Before)
+	}
+
+	var ids []int32
+	if err := db.Order("id DESC").Limit(query.Size+1).Pluck("id", &ids).Error; err != nil {
+		return nil, false, err
+	}
+	if len(ids) > query.Size {
+		return ids[:query.Size], true, nil
+	}
+	return ids, false, nil
+}
+
+// createIndex 创建messages表上的全文索引，messages表还不存在时先建表
+func createIndex(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model.Message{}) {
+		if err := db.AutoMigrate(&model.Message{}); err != nil {
+			return err
+		}
+	}
+	if db.Migrator().HasIndex(&model.Message{}, fulltextIndex) {
+		return nil
+	}
+	return db.Exec("CREATE FULLTEXT INDEX " + fulltextIndex + " ON messages (content) WITH PARSER ngram").Error
+}
+
+// escapeLike 转义LIKE中的通配符
+func escapeLike(keyword string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword)
+}
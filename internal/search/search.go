@@ -0,0 +1,113 @@
+package search
+
+import (
+	"time"
+
+	"chat-room/config"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// Engine
+// @Description: 消息全文检索引擎，只负责按关键字和过滤条件查出消息id，消息内容仍从数据库中读取
+// @Description: mysql直接使用messages表上的FULLTEXT索引；local为进程内的倒排索引，适合单机部署
+type Engine interface {
+	// Index 新增或更新消息的索引，消息编辑后需要重新索引
+	Index(doc Document) error
+	// Remove 删除消息的索引，消息撤回后不再能被搜索到
+	Remove(id int32) error
+	// Search 按消息id倒序返回匹配的消息id，bool表示是否还有更多结果
+	Search(query Query) ([]int32, bool, error)
+}
+
+// Document 需要索引的消息
+type Document struct {
+	ID          int32
+	MessageType int16
+	FromUserId  int32
+	ToUserId    int32 // 单聊为接收者id，群聊为群id
+	ContentType int16
+	Content     string
+	CreatedAt   time.Time
+}
+
+// Query 检索条件，可搜索的范围由调用方根据用户所在的会话确定
+type Query struct {
+	Keyword     string
+	UserId      int32   // 搜索者，单聊消息的收发一方需为该用户
+	Direct      bool    // 是否搜索单聊消息
+	PeerId      int32   // 大于0时只搜索与该用户的单聊
+	GroupIds    []int32 // 可搜索的群
+	FromUserId  int32   // 大于0时只搜索该用户发送的消息
+	ContentType int16   // 大于0时只搜索该类型的消息
+	Start       time.Time
+	End         time.Time
+	Before      int32 // 游标：只返回id小于该值的消息
+	Size        int
+}
+
+// inScope 消息是否在检索范围内
+func (q *Query) inScope(doc *Document) bool {
+	if doc.MessageType == constant.MESSAGE_TYPE_GROUP {
+		for _, groupId := range q.GroupIds {
+			if doc.ToUserId == groupId {
+				return true
+			}
+		}
+		return false
+	}
+	if !q.Direct {
+		return false
+	}
+	if doc.FromUserId == q.UserId {
+		return q.PeerId == 0 || doc.ToUserId == q.PeerId
+	}
+	if doc.ToUserId == q.UserId {
+		return q.PeerId == 0 || doc.FromUserId == q.PeerId
+	}
+	return false
+}
+
+// matches 消息是否满足发送者、类型、时间和游标条件
+func (q *Query) matches(doc *Document) bool {
+	if q.FromUserId > 0 && doc.FromUserId != q.FromUserId {
+		return false
+	}
+	if q.ContentType > 0 && doc.ContentType != q.ContentType {
+		return false
+	}
+	if !q.Start.IsZero() && doc.CreatedAt.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && !doc.CreatedAt.Before(q.End) {
+		return false
+	}
+	if q.Before > 0 && doc.ID >= q.Before {
+		return false
+	}
+	return q.inScope(doc)
+}
+
+// New
+//
+//	@Description: 根据配置创建检索引擎，local引擎启动时从数据库加载全部消息建立索引
+//	@param conf
+//	@param db
+//	@return Engine
+//	@return error
+func New(conf config.TomlConfig, db *gorm.DB) (Engine, error) {
+	switch conf.Search.Engine {
+	case constant.MYSQL, "":
+		return NewMySQLEngine(db)
+	case constant.LOCAL:
+		engine := NewMemoryEngine()
+		if err := engine.Load(db); err != nil {
+			return nil, err
+		}
+		return engine, nil
+	default:
+		return nil, errors.New("不支持的检索引擎类型: " + conf.Search.Engine)
+	}
+}
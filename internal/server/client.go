@@ -1,19 +1,20 @@
 package server
 
 import (
-	"chat-room/config"
-	"chat-room/internal/kafka"
+	"chat-room/internal/service"
 	"chat-room/pkg/common/constant"
 	"chat-room/pkg/global/log"
 	"chat-room/pkg/protocol"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type Client struct {
 	Conn *websocket.Conn
-	Name string
+	Name string // 用户uuid
+	Id   string // 设备连接id，同一用户的每个设备(或浏览器标签页)各不相同
 	Send chan []byte
 }
 
@@ -47,16 +48,99 @@ func (c *Client) Read() {
 				log.Logger.Error("client marshal message error", log.Any("client marshal message error", err2.Error()))
 			}
 			c.Conn.WriteMessage(websocket.BinaryMessage, pongByte)
+		} else if msg.Type == constant.PRESENCE {
+			// 客户端上报离开(away)或回到在线(online)
+			MyServer.SetAway(c.Name, msg.Content == constant.PRESENCE_AWAY)
+		} else if msg.Type == constant.DELIVERED || msg.Type == constant.READ {
+			// 接收端上报消息回执，msgId为服务端分配的消息id
+			handleReceipt(c.Name, msg)
+		} else if isOperation(msg) {
+			// 撤回、删除、编辑、表情回应等消息操作，msgId为服务端分配的消息id
+			c.operate(msg)
+		} else if msg.Type == constant.SYNC {
+			// 客户端重连后同步离线期间缺失的消息
+			c.sync(msg)
+		} else if !isClientMessage(msg) {
+			// 回执、错误、移出群聊等只能由服务端生成，客户端发来的直接拒绝，避免伪造
+			c.sendMessage(&protocol.Message{Type: constant.ERROR, Content: "不支持的消息类型", ClientMsgId: msg.ClientMsgId})
 		} else {
-			if config.GetConfig().MsgChannelType.ChannelType == constant.KAFKA {
-				kafka.Send(message)
-			} else {
-				MyServer.Broadcast <- message
+			// 发送者以连接的登录身份为准，并记录发送设备，以便同步给该用户的其他设备
+			msg.From = c.Name
+			msg.Device = c.Id
+			// 群聊消息在保存和投递前校验发送者是否为群成员、是否被禁言
+			if msg.To != "" && msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+				if err = service.GroupService.CheckSend(c.Name, msg.To); err != nil {
+					c.sendMessage(&protocol.Message{Type: constant.ERROR, Content: err.Error(), ClientMsgId: msg.ClientMsgId})
+					continue
+				}
+			}
+			// 普通消息在接收该消息的节点上保存，分布式部署时也只会保存一次
+			if msg.To != "" && isContentMessage(msg) {
+				if msg.ClientMsgId == "" {
+					msg.ClientMsgId = uuid.NewString() // 兼容未生成消息id的旧客户端
+				}
+				duplicate, err := saveMessage(msg)
+				if err != nil {
+					log.Logger.Error("client save message error", log.Any("client save message error", err.Error()))
+					c.sendMessage(&protocol.Message{Type: constant.ERROR, Content: err.Error(), ClientMsgId: msg.ClientMsgId})
+					continue
+				}
+				c.ack(msg)
+				// 重发的消息在第一次发送时已经投递过，只需回复回执
+				if duplicate {
+					continue
+				}
+			}
+			if err = MyServer.Publish(msg); err != nil {
+				log.Logger.Error("client publish message error", log.Any("client publish message error", err.Error()))
 			}
 		}
 	}
 }
 
+// ack 回复消息回执给发送消息的设备
+func (c *Client) ack(msg *protocol.Message) {
+	c.sendMessage(newAck(msg))
+}
+
+// isClientMessage 客户端可以发送给其他用户的消息：普通消息和音视频通话信令，系统消息只能由服务端生成
+func isClientMessage(msg *protocol.Message) bool {
+	if msg.Type != "" && msg.Type != constant.WEBRTC {
+		return false
+	}
+	return msg.ContentType != constant.SYSTEM
+}
+
+// isOperation 客户端发起的针对某条消息的操作
+func isOperation(msg *protocol.Message) bool {
+	switch msg.Type {
+	case constant.RECALL, constant.DELETE, constant.EDIT, constant.REACT, constant.UNREACT, constant.PIN, constant.UNPIN:
+		return true
+	default:
+		return false
+	}
+}
+
+// operate 处理客户端发起的消息操作，操作失败时回复错误
+func (c *Client) operate(msg *protocol.Message) {
+	var err error
+	switch msg.Type {
+	case constant.RECALL:
+		err = MyServer.Recall(c.Name, c.Id, msg.MsgId)
+	case constant.DELETE:
+		err = MyServer.Delete(c.Name, c.Id, msg.MsgId)
+	case constant.EDIT:
+		err = MyServer.Edit(c.Name, c.Id, msg.MsgId, msg.Content)
+	case constant.REACT, constant.UNREACT:
+		err = MyServer.React(c.Name, c.Id, msg.MsgId, msg.Content, msg.Type == constant.REACT)
+	case constant.PIN, constant.UNPIN:
+		err = MyServer.Pin(c.Name, c.Id, msg.MsgId, msg.Type == constant.PIN)
+	}
+	if err != nil {
+		c.sendMessage(&protocol.Message{Type: constant.ERROR, Content: err.Error(), MsgId: msg.MsgId})
+	}
+}
+
 func (c *Client) Write() {
 	defer func() {
 		c.Conn.Close()
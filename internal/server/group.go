@@ -0,0 +1,75 @@
+package server
+
+import (
+	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/response"
+)
+
+// LeaveGroup 退出群聊，并将系统消息推送给其余成员
+func (s *Server) LeaveGroup(userUuid string, groupUuid string) error {
+	message, err := service.GroupService.LeaveGroup(userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	return s.publishSystemMessage(groupUuid, message)
+}
+
+// RemoveMember 将成员移出群聊(ban为true时同时禁止再次加入)，将系统消息推送给其余成员并通知被移出的成员
+func (s *Server) RemoveMember(userUuid string, groupUuid string, memberUuid string, ban bool) error {
+	message, err := service.GroupService.RemoveMember(userUuid, groupUuid, memberUuid, ban)
+	if err != nil || message == nil {
+		return err
+	}
+	if err = s.publishSystemMessage(groupUuid, message); err != nil {
+		return err
+	}
+	// 被移出的成员已经查不到，单独通知其所有设备
+	msg := toProtocol(message)
+	msg.Type = constant.REMOVE
+	msg.From = groupUuid
+	msg.To = memberUuid
+	return s.Publish(msg)
+}
+
+// publishSystemMessage 系统消息与普通群聊消息一样推送给群中的所有成员
+func (s *Server) publishSystemMessage(groupUuid string, message *response.MessageResponse) error {
+	msg := toProtocol(message)
+	msg.From = message.FromUuid
+	msg.To = groupUuid
+	return s.Publish(msg)
+}
+
+// TransferOwner 转让群主，并将系统消息推送给群成员
+func (s *Server) TransferOwner(userUuid string, groupUuid string, ownerUuid string) error {
+	message, err := service.GroupService.TransferOwner(userUuid, groupUuid, ownerUuid)
+	if err != nil {
+		return err
+	}
+	return s.publishSystemMessage(groupUuid, message)
+}
+
+// DissolveGroup
+//
+//	@Description: 解散群聊。解散后已经查不到群成员，不能再按群聊消息推送，
+//	@Description: 改为给解散时的每个成员单独发布一条解散事件，携带系统消息的内容和序号
+//	@receiver s
+//	@param userUuid
+//	@param groupUuid
+//	@return error
+func (s *Server) DissolveGroup(userUuid string, groupUuid string) error {
+	message, members, err := service.GroupService.DissolveGroup(userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		msg := toProtocol(message)
+		msg.Type = constant.DISSOLVE
+		msg.From = groupUuid
+		msg.To = member
+		if err = s.Publish(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
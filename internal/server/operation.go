@@ -0,0 +1,147 @@
+package server
+
+import (
+	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+	"chat-room/pkg/protocol"
+)
+
+// Recall
+//
+//	@Description: 撤回消息，并通知会话中的在线设备移除该消息
+//	@receiver s
+//	@param userUuid 操作者
+//	@param device 发起操作的设备连接id，通过REST接口操作时为空
+//	@param msgId
+//	@return error
+func (s *Server) Recall(userUuid string, device string, msgId int64) error {
+	target, err := service.MessageService.RecallMessage(userUuid, msgId)
+	if err != nil {
+		return err
+	}
+	return s.publishEvent(&protocol.Message{
+		Type:        constant.RECALL,
+		From:        userUuid,
+		To:          target.To,
+		MessageType: target.MessageType,
+		MsgId:       target.MsgId,
+		Seq:         target.Seq,
+		Device:      device,
+	})
+}
+
+// Delete 删除消息(仅自己不可见)，并通知自己的其他设备
+func (s *Server) Delete(userUuid string, device string, msgId int64) error {
+	if err := service.MessageService.DeleteMessage(userUuid, msgId); err != nil {
+		return err
+	}
+	return s.publishEvent(&protocol.Message{
+		Type:        constant.DELETE,
+		From:        userUuid,
+		To:          userUuid,
+		MessageType: constant.MESSAGE_TYPE_USER,
+		MsgId:       msgId,
+		Device:      device,
+	})
+}
+
+// Edit 编辑消息，并将编辑后的内容推送给会话中的在线设备
+func (s *Server) Edit(userUuid string, device string, msgId int64, content string) error {
+	target, editedAt, err := service.MessageService.EditMessage(userUuid, msgId, content)
+	if err != nil {
+		return err
+	}
+	return s.publishEvent(&protocol.Message{
+		Type:        constant.EDIT,
+		From:        userUuid,
+		To:          target.To,
+		Content:     content,
+		MessageType: target.MessageType,
+		MsgId:       target.MsgId,
+		Seq:         target.Seq,
+		EditedAt:    editedAt,
+		Device:      device,
+	})
+}
+
+// React 添加(add为true)或取消表情回应，并推送给会话中的在线设备
+func (s *Server) React(userUuid string, device string, msgId int64, emoji string, add bool) error {
+	target, changed, err := service.ReactionService.React(userUuid, msgId, emoji, add)
+	if err != nil || !changed {
+		return err
+	}
+	eventType := constant.REACT
+	if !add {
+		eventType = constant.UNREACT
+	}
+	return s.publishEvent(&protocol.Message{
+		Type:        eventType,
+		From:        userUuid,
+		To:          target.To,
+		Content:     emoji,
+		MessageType: target.MessageType,
+		MsgId:       target.MsgId,
+		Seq:         target.Seq,
+		Device:      device,
+	})
+}
+
+// Forward 转发消息，保存后与普通消息一样推送给接收方以及转发者的其他设备
+func (s *Server) Forward(userUuid string, device string, req request.ForwardRequest) ([]response.MessageResponse, error) {
+	messages, err := service.ForwardService.Forward(userUuid, req)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		msg := toProtocol(&messages[i])
+		msg.From = userUuid
+		msg.To = req.To
+		msg.Device = device
+		if err = s.Publish(msg); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// Pin 置顶(pin为true)或取消置顶消息，并推送给会话中的在线设备
+func (s *Server) Pin(userUuid string, device string, msgId int64, pin bool) error {
+	target, changed, err := service.PinService.Pin(userUuid, msgId, pin)
+	if err != nil || !changed {
+		return err
+	}
+	eventType := constant.PIN
+	if !pin {
+		eventType = constant.UNPIN
+	}
+	return s.publishEvent(&protocol.Message{
+		Type:        eventType,
+		From:        userUuid,
+		To:          target.To,
+		MessageType: target.MessageType,
+		MsgId:       target.MsgId,
+		Seq:         target.Seq,
+		Device:      device,
+	})
+}
+
+// publishEvent 发布消息操作事件，事件必须有明确的接收方，目标为空时会被当作广播推送给所有在线用户
+func (s *Server) publishEvent(msg *protocol.Message) error {
+	if msg.To == "" {
+		return errors.New("消息所在的会话不存在")
+	}
+	return s.Publish(msg)
+}
+
+// isConversationEvent 撤回、编辑、表情回应、置顶等针对会话中某条消息的事件，与普通消息一样推送给会话中的所有人
+func isConversationEvent(msg *protocol.Message) bool {
+	switch msg.Type {
+	case constant.RECALL, constant.EDIT, constant.REACT, constant.UNREACT, constant.PIN, constant.UNPIN:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"chat-room/internal/presence"
+	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/global/log"
+	"chat-room/pkg/protocol"
+)
+
+const defaultPresenceDebounce = 3 * time.Second
+
+// presenceNotifier
+// @Description: 在线状态变化通知的防抖：用户的状态变化后等待一段时间再推送，
+// @Description: 期间再次变化会重新计时，连接不稳定时频繁断线重连不会打扰好友
+type presenceNotifier struct {
+	mutex  sync.Mutex
+	delay  time.Duration
+	timers map[string]*time.Timer
+	notify func(userUuid string)
+}
+
+func newPresenceNotifier(delay time.Duration, notify func(userUuid string)) *presenceNotifier {
+	if delay <= 0 {
+		delay = defaultPresenceDebounce
+	}
+	return &presenceNotifier{
+		delay:  delay,
+		timers: make(map[string]*time.Timer),
+		notify: notify,
+	}
+}
+
+// touch 用户状态可能发生了变化，重新开始计时
+func (n *presenceNotifier) touch(userUuid string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if timer, ok := n.timers[userUuid]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(n.delay, func() {
+		n.mutex.Lock()
+		if n.timers[userUuid] == timer {
+			delete(n.timers, userUuid)
+		}
+		n.mutex.Unlock()
+		n.notify(userUuid)
+	})
+	n.timers[userUuid] = timer
+}
+
+// SetAway 客户端上报用户是否处于离开状态(长时间无操作)
+func (s *Server) SetAway(userUuid string, away bool) {
+	if err := s.presence.SetAway(userUuid, away); err != nil {
+		log.Logger.Error("presence set away error", log.Any("presence set away error", err.Error()))
+		return
+	}
+	s.notifier.touch(userUuid)
+}
+
+// Status 用户当前的在线状态，多节点部署时为所有节点上的综合状态
+func (s *Server) Status(userUuid string) (presence.Status, error) {
+	return s.presence.Status(userUuid)
+}
+
+// notifyPresence
+//
+//	@Description: 防抖结束后查询用户的最终状态，与上次推送的状态不同时发布到总线，
+//	@Description: 由各节点推送给本节点上关注该用户的好友和群成员
+//	@receiver s
+//	@param userUuid
+func (s *Server) notifyPresence(userUuid string) {
+	status, err := s.presence.Status(userUuid)
+	if err != nil {
+		log.Logger.Error("query presence error", log.Any("query presence error", err.Error()))
+		return
+	}
+	changed, err := s.presence.Announce(userUuid, status.Status)
+	if err != nil {
+		log.Logger.Error("announce presence error", log.Any("announce presence error", err.Error()))
+		return
+	}
+	if !changed {
+		return
+	}
+	msg := &protocol.Message{
+		From:      userUuid,
+		Type:      constant.PRESENCE,
+		Content:   status.Status,
+		Timestamp: status.LastSeen,
+	}
+	if err = s.Publish(msg); err != nil {
+		log.Logger.Error("publish presence error", log.Any("publish presence error", err.Error()))
+	}
+}
+
+// sendPresence 将状态变化推送给本节点上在线的好友和群成员
+func (s *Server) sendPresence(userUuid string, data []byte) {
+	if len(s.Clients) == 0 {
+		return
+	}
+	for _, user := range service.UserService.GetPresenceAudience(userUuid) {
+		s.sendToUser(user, data, "")
+	}
+}
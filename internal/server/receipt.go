@@ -0,0 +1,36 @@
+package server
+
+import (
+	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/global/log"
+	"chat-room/pkg/protocol"
+)
+
+// handleReceipt
+//
+//	@Description: 接收端上报的送达/已读回执，保存后推送给消息发送者的所有设备
+//	@Description: 群聊消息的回执同样只推送给发送者，因此按单聊路由
+//	@param userUuid 上报回执的接收人
+//	@param msg
+func handleReceipt(userUuid string, msg *protocol.Message) {
+	senderUuid, at, changed, err := service.ReceiptService.SaveReceipt(userUuid, msg.MsgId, msg.Type)
+	if err != nil {
+		log.Logger.Error("save receipt error", log.Any("save receipt error", err.Error()))
+		return
+	}
+	if !changed {
+		return
+	}
+	receipt := &protocol.Message{
+		Type:        msg.Type,
+		From:        userUuid,
+		To:          senderUuid,
+		MessageType: constant.MESSAGE_TYPE_USER,
+		MsgId:       msg.MsgId,
+		Timestamp:   at,
+	}
+	if err = MyServer.Publish(receipt); err != nil {
+		log.Logger.Error("publish receipt error", log.Any("publish receipt error", err.Error()))
+	}
+}
@@ -2,15 +2,20 @@ package server
 
 import (
 	"chat-room/config"
+	"chat-room/internal/bus"
+	"chat-room/internal/model"
+	"chat-room/internal/presence"
 	"chat-room/internal/service"
 	"chat-room/pkg/common/constant"
 	"chat-room/pkg/common/util"
+	"chat-room/pkg/errors"
 	"chat-room/pkg/global/log"
 	"chat-room/pkg/protocol"
 	"encoding/base64"
 	"io/ioutil"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/google/uuid"
@@ -19,26 +24,140 @@ import (
 var MyServer = NewServer()
 
 type Server struct {
-	Clients   map[string]*Client // 维护用户-conn的映射 用户登录则加入map/用户离线则从map中删除
+	Clients   map[string]map[string]*Client // 维护用户-设备连接的映射 用户uuid -> 连接id -> conn，同一用户可以多端同时在线
 	mutex     *sync.Mutex
-	Broadcast chan []byte  // 广播消息
-	Register  chan *Client // 用户登录channel 有数据则说明有用户登进来
-	Offline   chan *Client // 用户离线channel 有数据则说明有用户离线
+	Broadcast chan []byte       // 广播消息
+	Register  chan *Client      // 用户登录channel 有数据则说明有用户登进来
+	Offline   chan *Client      // 用户离线channel 有数据则说明有用户离线
+	replies   chan deviceReply  // 回复给单个设备的消息(回执、错误、同步结果等)，统一由Start写入设备的发送通道
+	bus       bus.Bus           // 消息总线 客户端发来的消息先发布到总线，再由订阅回调放入Broadcast
+	presence  presence.Registry // 在线状态注册表 记录用户连接在哪些节点上
+	notifier  *presenceNotifier // 在线状态变化通知 防抖后推送给好友
 }
 
 func NewServer() *Server {
-	return &Server{
+	conf := config.GetConfig()
+	s := &Server{
 		mutex:     &sync.Mutex{},
-		Clients:   make(map[string]*Client),
+		Clients:   make(map[string]map[string]*Client),
 		Broadcast: make(chan []byte),
 		Register:  make(chan *Client),
 		Offline:   make(chan *Client),
+		replies:   make(chan deviceReply),
+		presence:  presence.NewLocalRegistry(conf.NodeId),
 	}
+	s.notifier = newPresenceNotifier(time.Duration(conf.Presence.Debounce)*time.Second, s.notifyPresence)
+	return s
 }
 
-// ConsumerKafkaMsg 消费kafka里面的消息, 然后直接放入go channel中统一进行消费
-func ConsumerKafkaMsg(data []byte) {
-	MyServer.Broadcast <- data
+// SetBus
+//
+//	@Description: 设置消息总线并订阅，总线上的消息直接放入Broadcast中统一进行消费
+//	@receiver s
+//	@param b
+//	@return error
+func (s *Server) SetBus(b bus.Bus) error {
+	s.bus = b
+	return b.Subscribe(func(data []byte) {
+		if !s.isLocal(data) {
+			return
+		}
+		s.Broadcast <- data
+	})
+}
+
+// isLocal
+//
+//	@Description: 节点本地路由：分布式部署时每个节点都会收到总线上的全部消息，
+//	@Description: 单聊消息的收发双方都不在本节点时直接丢弃，不再进入Broadcast处理
+//	@Description: 群聊消息需要查询群成员，交由Start处理；无接收人的广播消息所有节点都要处理
+//	@receiver s
+//	@param data
+//	@return bool
+func (s *Server) isLocal(data []byte) bool {
+	msg := &protocol.Message{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return false
+	}
+	if msg.To == "" || msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		return true
+	}
+	return s.isOnline(msg.To) || s.isOnline(msg.From)
+}
+
+// isOnline 用户是否有设备连接在本节点
+func (s *Server) isOnline(userUuid string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.Clients[userUuid]) > 0
+}
+
+// SetPresence 设置在线状态注册表，多节点部署时需使用节点间共享的注册表
+func (s *Server) SetPresence(r presence.Registry) {
+	s.presence = r
+	// 其他节点宕机时，其上的用户经防抖后推送离线
+	r.OnChange(s.notifier.touch)
+}
+
+// Publish
+//
+//	@Description: 发布消息到消息总线
+//	@Description: 单聊消息只定向发布给收发双方连接所在的节点，群聊和广播消息发布给所有节点
+//	@receiver s
+//	@param msg
+//	@return error
+func (s *Server) Publish(msg *protocol.Message) error {
+	if s.bus == nil {
+		return errors.New("消息总线未初始化")
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	key := conversationKey(msg)
+	if msg.To == "" || msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		return s.bus.Publish(key, data)
+	}
+
+	nodes, err := s.targetNodes(msg.To, msg.From)
+	if err != nil {
+		// 注册表不可用时退化为发布给所有节点，由各节点自行过滤
+		log.Logger.Error("query presence error", log.Any("query presence error", err.Error()))
+		return s.bus.Publish(key, data)
+	}
+	for _, node := range nodes {
+		if err = s.bus.PublishTo(node, key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// targetNodes 持有这些用户连接的节点(去重)
+func (s *Server) targetNodes(users ...string) ([]string, error) {
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, user := range users {
+		userNodes, err := s.presence.Nodes(user)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range userNodes {
+			if !seen[node] {
+				seen[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// conversationKey 消息所属的会话，作为消息队列的分区key，保证同一会话内的消息有序
+func conversationKey(msg *protocol.Message) string {
+	if msg.To == "" {
+		return msg.From
+	}
+	return util.ConversationId(msg.MessageType, msg.From, msg.To)
 }
 
 // Start 启动服务器
@@ -47,8 +166,23 @@ func (s *Server) Start() {
 	for {
 		select {
 		case conn := <-s.Register: // 有用户接进来
-			log.Logger.Info("login", log.Any("login", "new user login in. uuid|"+conn.Name))
-			s.Clients[conn.Name] = conn
+			log.Logger.Info("login", log.Any("login", "new user login in. uuid|"+conn.Name+"|device|"+conn.Id))
+			s.mutex.Lock()
+			_, online := s.Clients[conn.Name]
+			if !online {
+				s.Clients[conn.Name] = make(map[string]*Client)
+			}
+			s.Clients[conn.Name][conn.Id] = conn
+			s.mutex.Unlock()
+			// 用户在本节点的首个设备上线时登记到注册表，其他设备上线说明用户已不再离开
+			if !online {
+				if err := s.presence.Online(conn.Name); err != nil {
+					log.Logger.Error("presence online error", log.Any("presence online error", err.Error()))
+				}
+			} else if err := s.presence.SetAway(conn.Name, false); err != nil {
+				log.Logger.Error("presence set away error", log.Any("presence set away error", err.Error()))
+			}
+			s.notifier.touch(conn.Name)
 			msg := &protocol.Message{
 				From:    "System",
 				To:      conn.Name,
@@ -57,12 +191,17 @@ func (s *Server) Start() {
 			protoMsg, _ := proto.Marshal(msg)
 			conn.Send <- protoMsg
 
+		case reply := <-s.replies: // 回复给单个设备
+			// 发送通道只在Start中关闭，设备已下线时通道已关闭，直接丢弃
+			if s.Clients[reply.client.Name][reply.client.Id] == reply.client {
+				reply.client.Send <- reply.data
+			}
+
 		case conn := <-s.Offline: // 用户离线
-			log.Logger.Info("logout", log.Any("logout. uuid|", conn.Name))
-			if _, ok := s.Clients[conn.Name]; ok {
-				close(conn.Send)
-				//_ = conn.Conn.Close()        // 原代码中没有关闭连接的操作 这里要不要加? todo
-				delete(s.Clients, conn.Name) // map中删除已经离线的用户
+			log.Logger.Info("logout", log.Any("logout. uuid|", conn.Name+"|device|"+conn.Id))
+			// 只下线对应的设备，同一用户的其他设备不受影响
+			if _, ok := s.Clients[conn.Name][conn.Id]; ok {
+				s.removeClient(conn)
 			}
 
 		case message := <-s.Broadcast:
@@ -71,21 +210,23 @@ func (s *Server) Start() {
 			if err != nil {
 				log.Logger.Error("broadcast msg unmarshal", log.Any("err|", err))
 			}
-			if msg.To != "" {
-				if msg.ContentType >= constant.TEXT && msg.ContentType <= constant.VIDEO { // 普通消息-文本/文件/图片/语音/视频等
-					// 保存消息只会在存在socket的一个端上进行保存，防止分布式部署后，消息重复问题
-					_, exits := s.Clients[msg.From]
-					if exits {
-						// 1.保存消息
-						saveMessage(msg)
-					}
-					// 2.转发至对应客户端的消息接收通道
+			if msg.Type == constant.PRESENCE {
+				// 好友的在线状态变化
+				s.sendPresence(msg.From, message)
+			} else if msg.Type == constant.REMOVE || msg.Type == constant.DISSOLVE {
+				// 被移出或群聊已解散，接收人已不是群成员，发布时已经逐个指定了接收人
+				s.sendToUser(msg.To, message, "")
+			} else if msg.To != "" {
+				if isContentMessage(msg) || isConversationEvent(msg) {
+					// 消息已经在接收客户端消息的节点上保存(见Client.Read)，这里只负责转发至对应客户端的消息接收通道
+					// 撤回等会话事件同样推送给会话中的所有人
 					if msg.MessageType == constant.MESSAGE_TYPE_USER { // 单聊
-						client, ok := s.Clients[msg.To]
-						if ok {
-							msgByte, err := proto.Marshal(msg)
-							if err == nil {
-								client.Send <- msgByte
+						msgByte, err := proto.Marshal(msg)
+						if err == nil {
+							s.sendToUser(msg.To, msgByte, "")
+							// 同步给发送者的其他设备
+							if msg.From != msg.To {
+								s.sendToUser(msg.From, msgByte, msg.Device)
 							}
 						}
 					} else if msg.MessageType == constant.MESSAGE_TYPE_GROUP { // 群聊
@@ -96,22 +237,20 @@ func (s *Server) Start() {
 						语音电话，视频电话等，仅支持单人聊天，不支持群聊 当然可以扩展 todo
 						不保存文件，直接进行转发
 					*/
-					client, ok := s.Clients[msg.To]
-					if ok {
-						client.Send <- message
-					}
+					s.sendToUser(msg.To, message, "")
 				}
 
 			} else {
 				// 无对应接受人员进行广播
-				for id, conn := range s.Clients {
+				for id, devices := range s.Clients {
 					log.Logger.Info("allUser", log.Any("allUser", id))
 
-					select {
-					case conn.Send <- message:
-					default:
-						close(conn.Send) // 关闭某个连接的消息发送通道
-						delete(s.Clients, conn.Name)
+					for _, conn := range devices {
+						select {
+						case conn.Send <- message:
+						default:
+							s.removeClient(conn) // 关闭某个连接的消息发送通道
+						}
 					}
 				}
 			}
@@ -119,43 +258,104 @@ func (s *Server) Start() {
 	}
 }
 
+// deviceReply 回复给单个设备的消息
+type deviceReply struct {
+	client *Client
+	data   []byte
+}
+
+// reply 回复消息给单个设备。设备的读协程不能直接写入发送通道，设备下线时Start会关闭该通道
+func (s *Server) reply(client *Client, data []byte) {
+	s.replies <- deviceReply{client: client, data: data}
+}
+
+// sendToUser 发送消息给用户在本机的所有在线设备，exceptDevice 不为空时跳过该设备(通常是消息的发送端)
+func (s *Server) sendToUser(userUuid string, data []byte, exceptDevice string) {
+	for id, client := range s.Clients[userUuid] {
+		if id == exceptDevice {
+			continue
+		}
+		client.Send <- data
+	}
+}
+
+// removeClient 关闭设备连接的消息发送通道，并从map中删除；用户的最后一个设备下线时删除该用户
+// Clients只在Start协程中修改，修改时加锁，供其他协程(如总线订阅回调)并发读取
+func (s *Server) removeClient(conn *Client) {
+	close(conn.Send)
+	//_ = conn.Conn.Close()        // 原代码中没有关闭连接的操作 这里要不要加? todo
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.Clients[conn.Name], conn.Id)
+	if len(s.Clients[conn.Name]) == 0 {
+		delete(s.Clients, conn.Name) // map中删除已经离线的用户
+		if err := s.presence.Offline(conn.Name); err != nil {
+			log.Logger.Error("presence offline error", log.Any("presence offline error", err.Error()))
+		}
+		s.notifier.touch(conn.Name)
+	}
+}
+
+// isContentMessage 普通消息-文本/文件/图片/语音/视频以及聊天记录、系统消息等，需要保存；语音电话、视频电话等只转发不保存
+func isContentMessage(msg *protocol.Message) bool {
+	return (msg.ContentType >= constant.TEXT && msg.ContentType <= constant.VIDEO) ||
+		msg.ContentType == constant.CHAT_RECORD || msg.ContentType == constant.SYSTEM
+}
+
 // sendGroupMessage 发送给群组消息,需要查询该群所有人员依次发送
 func sendGroupMessage(msg *protocol.Message, s *Server) {
 	// 发送给群组的消息，查找该群所有的用户进行发送
 	users := service.GroupService.GetUserIdByGroupUuid(msg.To)
+	fromUserDetails := service.UserService.GetUserDetails(msg.From)
+	// 由于发送群聊时，from是个人，to是群聊uuid。所以在返回消息时，将form修改为群聊uuid，和单聊进行统一
+	msgSend := protocol.Message{
+		Avatar:       fromUserDetails.Avatar,
+		FromUsername: msg.FromUsername,
+		From:         msg.To,
+		To:           msg.From,
+		Content:      msg.Content,
+		ContentType:  msg.ContentType,
+		Type:         msg.Type,
+		MessageType:  msg.MessageType,
+		Url:          msg.Url,
+		Device:       msg.Device,
+		Timestamp:    msg.Timestamp,
+		ClientMsgId:  msg.ClientMsgId,
+		MsgId:        msg.MsgId,
+		Seq:          msg.Seq,
+		EditedAt:     msg.EditedAt,
+		ReplyTo:      msg.ReplyTo,
+		ThreadRoot:   msg.ThreadRoot,
+		Quote:        msg.Quote,
+		QuoteFrom:    msg.QuoteFrom,
+		Mentions:     msg.Mentions,
+
+		ForwardFrom:         msg.ForwardFrom,
+		ForwardFromUsername: msg.ForwardFromUsername,
+	}
+	msgByte, err := proto.Marshal(&msgSend)
+	if err != nil {
+		return
+	}
+
 	for _, user := range users {
+		// 发送者自己的其他设备也需要收到这条消息
 		if user.Uuid == msg.From {
+			s.sendToUser(user.Uuid, msgByte, msg.Device)
 			continue
 		}
-
-		client, ok := s.Clients[user.Uuid]
-		if !ok {
-			continue
-		}
-
-		fromUserDetails := service.UserService.GetUserDetails(msg.From)
-		// 由于发送群聊时，from是个人，to是群聊uuid。所以在返回消息时，将form修改为群聊uuid，和单聊进行统一
-		msgSend := protocol.Message{
-			Avatar:       fromUserDetails.Avatar,
-			FromUsername: msg.FromUsername,
-			From:         msg.To,
-			To:           msg.From,
-			Content:      msg.Content,
-			ContentType:  msg.ContentType,
-			Type:         msg.Type,
-			MessageType:  msg.MessageType,
-			Url:          msg.Url,
-		}
-
-		msgByte, err := proto.Marshal(&msgSend)
-		if err == nil {
-			client.Send <- msgByte
-		}
+		s.sendToUser(user.Uuid, msgByte, "")
 	}
 }
 
 // saveMessage 保存消息，如果是文本消息直接保存; 如果是文件、语音等消息，保存文件到配置指定路径后，保存对应的文件路径
-func saveMessage(message *protocol.Message) {
+// 保存后将服务端分配的消息id、序号和时间回填到message中；客户端重发已保存过的消息时返回true，不再重复保存
+func saveMessage(message *protocol.Message) (bool, error) {
+	if saved := service.MessageService.GetSavedMessage(message.From, message.ClientMsgId); saved != nil {
+		fillSaved(message, saved)
+		return true, nil
+	}
+
 	// 如果上传的是base64字符串文件，解析文件保存
 	if message.ContentType == 2 {
 		url := uuid.New().String() + ".png"
@@ -168,12 +368,12 @@ func saveMessage(message *protocol.Message) {
 		dataBuffer, dataErr := base64.StdEncoding.DecodeString(content)
 		if dataErr != nil {
 			log.Logger.Error("transfer base64 to file error", log.String("transfer base64 to file error", dataErr.Error()))
-			return
+			return false, dataErr
 		}
 		err := ioutil.WriteFile(config.GetConfig().StaticPath.FilePath+url, dataBuffer, 0666)
 		if err != nil {
 			log.Logger.Error("write file error", log.String("write file error", err.Error()))
-			return
+			return false, err
 		}
 		message.Url = url
 		message.Content = ""
@@ -189,12 +389,56 @@ func saveMessage(message *protocol.Message) {
 		err := ioutil.WriteFile(config.GetConfig().StaticPath.FilePath+url, message.File, 0666)
 		if err != nil {
 			log.Logger.Error("write file error", log.String("write file error", err.Error()))
-			return
+			return false, err
 		}
 		message.Url = url
 		message.File = nil
 		message.ContentType = contentType
 	}
 
-	service.MessageService.SaveMessage(*message)
+	saved, duplicate, err := service.MessageService.SaveMessage(*message)
+	if err != nil {
+		return false, err
+	}
+	fillSaved(message, saved)
+	return duplicate, nil
+}
+
+// fillSaved 回填服务端保存消息时分配的字段
+func fillSaved(message *protocol.Message, saved *model.Message) {
+	message.MsgId = int64(saved.ID)
+	message.Seq = saved.Seq
+	message.Timestamp = saved.CreatedAt.UnixMilli()
+	message.Url = saved.Url
+	message.Content = saved.Content
+	message.ContentType = int32(saved.ContentType)
+	message.ReplyTo = int64(saved.ReplyTo)
+	message.ThreadRoot = int64(saved.ThreadRoot)
+	message.Mentions = splitMentions(saved.Mentions)
+	// 回复消息附带被回复消息的摘要，接收方无需再查询被回复的消息
+	if saved.ReplyTo > 0 {
+		message.Quote, message.QuoteFrom = service.MessageService.GetQuote(saved.ReplyTo)
+	}
+}
+
+// splitMentions 保存的@列表为逗号分隔的成员uuid
+func splitMentions(mentions string) []string {
+	if mentions == "" {
+		return nil
+	}
+	return strings.Split(mentions, ",")
+}
+
+// newAck 消息保存后回复给发送端的回执，携带客户端消息id以及服务端分配的消息id
+func newAck(message *protocol.Message) *protocol.Message {
+	return &protocol.Message{
+		Type:        constant.ACK,
+		To:          message.To,
+		MessageType: message.MessageType,
+		ClientMsgId: message.ClientMsgId,
+		MsgId:       message.MsgId,
+		Seq:         message.Seq,
+		Timestamp:   message.Timestamp,
+		ThreadRoot:  message.ThreadRoot,
+	}
 }
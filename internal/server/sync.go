@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+
+	"chat-room/internal/service"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
+	"chat-room/pkg/global/log"
+	"chat-room/pkg/protocol"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// syncResult 同步完成后回复给客户端的每个会话的同步结果
+type syncResult struct {
+	ConversationId string `json:"conversationId"`
+	Seq            int64  `json:"seq"`     // 本次同步到的最大序号
+	HasMore        bool   `json:"hasMore"` // 还有未同步的消息，客户端需要从seq继续同步
+}
+
+// sync
+//
+//	@Description: 处理客户端的离线消息同步请求，content为request.SyncRequest的json
+//	@Description: 缺失的消息按序号依次补发给发起同步的设备，最后回复一条sync消息说明各会话的同步结果
+//	@receiver c
+//	@param msg
+func (c *Client) sync(msg *protocol.Message) {
+	var req request.SyncRequest
+	if err := json.Unmarshal([]byte(msg.Content), &req); err != nil {
+		c.sendError("同步请求格式错误")
+		return
+	}
+	conversations, err := service.MessageService.SyncMessages(c.Name, req)
+	if err != nil {
+		c.sendError(err.Error())
+		return
+	}
+
+	results := make([]syncResult, 0, len(conversations))
+	for _, conversation := range conversations {
+		seq := int64(0)
+		for i := range conversation.Messages {
+			message := &conversation.Messages[i]
+			c.sendMessage(toProtocol(message))
+			seq = message.Seq
+		}
+		// 没有缺失的消息时保持客户端上报的序号
+		if seq == 0 {
+			for _, reqConversation := range req.Conversations {
+				if reqConversation.ConversationId == conversation.ConversationId {
+					seq = reqConversation.Seq
+				}
+			}
+		}
+		results = append(results, syncResult{ConversationId: conversation.ConversationId, Seq: seq, HasMore: conversation.HasMore})
+	}
+	content, _ := json.Marshal(results)
+	c.sendMessage(&protocol.Message{Type: constant.SYNC, Content: string(content)})
+}
+
+// sendMessage 发送消息给当前设备
+func (c *Client) sendMessage(msg *protocol.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("client marshal message error", log.Any("client marshal message error", err.Error()))
+		return
+	}
+	MyServer.reply(c, data)
+}
+
+// sendError 回复错误消息给当前设备
+func (c *Client) sendError(reason string) {
+	c.sendMessage(&protocol.Message{Type: constant.ERROR, Content: reason})
+}
+
+// toProtocol 将保存的消息转换为推送给客户端的格式，与实时推送的消息保持一致：
+// 单聊的from为发送者、to为接收者；群聊的from为群uuid、to为发送者
+func toProtocol(message *response.MessageResponse) *protocol.Message {
+	msg := &protocol.Message{
+		Avatar:       message.Avatar,
+		FromUsername: message.FromUsername,
+		From:         message.FromUuid,
+		Content:      message.Content,
+		ContentType:  int32(message.ContentType),
+		MessageType:  int32(message.MessageType),
+		Url:          message.Url,
+		Timestamp:    message.CreatedAt.UnixMilli(),
+		ClientMsgId:  message.ClientMsgId,
+		MsgId:        int64(message.ID),
+		Seq:          message.Seq,
+		EditedAt:     message.EditedAt,
+		ReplyTo:      int64(message.ReplyTo),
+		ThreadRoot:   int64(message.ThreadRoot),
+		Quote:        message.Quote,
+		QuoteFrom:    message.QuoteFrom,
+		Mentions:     splitMentions(message.Mentions),
+
+		ForwardFrom:         message.ForwardFrom,
+		ForwardFromUsername: message.ForwardFromUsername,
+	}
+	// 已撤回的消息补发为撤回事件，客户端据此移除本地的消息
+	if message.Recalled {
+		msg.Type = constant.RECALL
+	}
+	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
+		msg.From = message.ConversationId
+		msg.To = message.FromUuid
+		return msg
+	}
+	msg.To = util.ConversationPeer(message.ConversationId, message.FromUuid)
+	return msg
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type conversationService struct {
+}
+
+var ConversationService = new(conversationService)
+
+// GetConversations
+//
+//	@Description: 获取用户的会话列表，包含最后一条消息、未读数以及免打扰、置顶设置
+//	@Description: 置顶的会话排在前面，其余按最后一条消息的时间倒序
+//	@receiver c
+//	@param userUuid
+//	@return []response.ConversationResponse
+//	@return error
+func (c *conversationService) GetConversations(userUuid string) ([]response.ConversationResponse, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	conversations := make([]response.ConversationResponse, 0)
+	db.Raw("SELECT c.conversation_id, c.message_type, c.muted, c.pinned, c.read_seq, COALESCE(s.seq, 0) AS seq, "+
+		"GREATEST(COALESCE(s.seq, 0) - c.read_seq, 0) AS unread, "+
+		"(SELECT COUNT(*) FROM message_mentions AS mm JOIN messages AS mn ON mn.id = mm.message_id AND mn.deleted_at = 0 "+
+		"WHERE mm.user_id = c.user_id AND mm.conversation_id = c.conversation_id AND mm.seq > c.read_seq) AS mention_unread, "+
+		"COALESCE(pu.uuid, g.uuid) AS uuid, COALESCE(pu.username, g.name) AS name, pu.avatar, "+
+		"m.id AS last_message_id, IF(m.deleted_at > 0, '', m.content) AS last_content, m.content_type AS last_content_type, m.created_at AS last_message_at, "+
+		"m.deleted_at > 0 AS last_recalled, fu.username AS last_from_username, g.deleted_at > 0 AS dissolved "+
+		"FROM conversations AS c "+
+		"LEFT JOIN conversation_sequences AS s ON s.conversation_id = c.conversation_id "+
+		"LEFT JOIN messages AS m ON m.id = s.last_message_id "+
+		"LEFT JOIN users AS fu ON fu.id = m.from_user_id "+
+		"LEFT JOIN users AS pu ON c.message_type = ? AND pu.id = c.peer_id "+
+		"LEFT JOIN `groups` AS g ON c.message_type = ? AND g.id = c.peer_id "+
+		"WHERE c.user_id = ? ORDER BY c.pinned DESC, m.created_at DESC",
+		constant.MESSAGE_TYPE_USER, constant.MESSAGE_TYPE_GROUP, user.Id).Scan(&conversations)
+	return conversations, nil
+}
+
+// ModifyConversation
+//
+//	@Description: 修改会话的免打扰、置顶设置，未传的字段保持不变
+//	@receiver c
+//	@param userUuid
+//	@param conversationId
+//	@param req
+//	@return error
+func (c *conversationService) ModifyConversation(userUuid string, conversationId string, req request.ConversationRequest) error {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return errors.New("用户不存在")
+	}
+	if !isParticipant(db, conversationId, &user) {
+		return errors.New("不是该会话的成员")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Muted != nil {
+		updates["muted"] = *req.Muted
+	}
+	if req.Pinned != nil {
+		updates["pinned"] = *req.Pinned
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := ensureConversation(db, &user, conversationId); err != nil {
+		return err
+	}
+	return db.Model(&model.Conversation{}).Where("user_id = ? AND conversation_id = ?", user.Id, conversationId).Updates(updates).Error
+}
+
+// updateConversations
+//
+//	@Description: 保存消息后更新会话：记录会话的最后一条消息，单聊为双方创建会话记录，
+//	@Description: 发送者自己发出的消息视为已读。与保存消息在同一事务中执行
+//	@param tx
+//	@param message 已分配序号的消息
+//	@return error
+func updateConversations(tx *gorm.DB, message *model.Message) error {
+	err := tx.Model(&model.ConversationSequence{}).Where("conversation_id = ?", message.ConversationId).
+		Update("last_message_id", message.ID).Error
+	if err != nil {
+		return err
+	}
+
+	conversations := []model.Conversation{{
+		UserId:         message.FromUserId,
+		ConversationId: message.ConversationId,
+		MessageType:    message.MessageType,
+		PeerId:         message.ToUserId,
+		ReadSeq:        message.Seq,
+	}}
+	// 群聊的其他成员在入群时已经创建了会话记录
+	if message.MessageType == constant.MESSAGE_TYPE_USER {
+		conversations = append(conversations, model.Conversation{
+			UserId:         message.ToUserId,
+			ConversationId: message.ConversationId,
+			MessageType:    message.MessageType,
+			PeerId:         message.FromUserId,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{"read_seq": gorm.Expr("GREATEST(read_seq, VALUES(read_seq))")}),
+	}).Create(&conversations).Error
+}
+
+// markRead 收到已读回执时将会话的已读位置推进到该消息，已读位置只增不减
+func markRead(db *gorm.DB, userId int32, conversationId string, seq int64) error {
+	return db.Model(&model.Conversation{}).Where("user_id = ? AND conversation_id = ? AND read_seq < ?", userId, conversationId, seq).
+		Update("read_seq", seq).Error
+}
+
+// joinConversation 用户入群时创建群聊会话，入群前的消息不计入未读
+func joinConversation(db *gorm.DB, userId int32, group *model.Group) error {
+	migrateMessageTables(db)
+	var sequence model.ConversationSequence
+	db.Where("conversation_id = ?", group.Uuid).Limit(1).Find(&sequence)
+	conversation := model.Conversation{
+		UserId:         userId,
+		ConversationId: group.Uuid,
+		MessageType:    constant.MESSAGE_TYPE_GROUP,
+		PeerId:         group.ID,
+		ReadSeq:        sequence.Seq,
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversation).Error
+}
+
+// ensureConversation 会话记录不存在时创建，用于在没有收发过消息的会话上修改设置
+func ensureConversation(db *gorm.DB, user *model.User, conversationId string) error {
+	var count int64
+	db.Model(&model.Conversation{}).Where("user_id = ? AND conversation_id = ?", user.Id, conversationId).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	if util.IsGroupConversation(conversationId) {
+		var group model.Group
+		db.Find(&group, "uuid = ?", conversationId)
+		if NULL_ID == group.ID {
+			return errors.New("群组不存在")
+		}
+		return joinConversation(db, user.Id, &group)
+	}
+
+	peerUuid := util.ConversationPeer(conversationId, user.Uuid)
+	var peer model.User
+	db.Find(&peer, "uuid = ?", peerUuid)
+	if NULL_ID == peer.Id {
+		return errors.New("用户不存在")
+	}
+	conversation := model.Conversation{
+		UserId:         user.Id,
+		ConversationId: conversationId,
+		MessageType:    constant.MESSAGE_TYPE_USER,
+		PeerId:         peer.Id,
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversation).Error
+}
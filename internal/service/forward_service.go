@@ -0,0 +1,259 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
+	"chat-room/pkg/errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type forwardService struct {
+}
+
+var ForwardService = new(forwardService)
+
+const (
+	maxForwardMessages = 100 // 单次最多转发的消息数
+	maxRecordDepth     = 5   // 聊天记录嵌套转发时向上查找可见性的最大层数
+	recordTitleNames   = 2   // 聊天记录标题中最多列出的发送者
+)
+
+// Forward
+//
+//	@Description: 转发消息到指定的用户或群，可以逐条转发或合并为一条聊天记录
+//	@Description: 转发的消息保留原发送者和文件地址，文件不重新上传；只能转发自己所在会话中未撤回的消息
+//	@receiver f
+//	@param userUuid 当前登录用户
+//	@param req
+//	@return []response.MessageResponse 转发后保存的消息
+//	@return error
+func (f *forwardService) Forward(userUuid string, req request.ForwardRequest) ([]response.MessageResponse, error) {
+	if len(req.MessageIds) == 0 {
+		return nil, errors.New("请选择要转发的消息")
+	}
+	if len(req.MessageIds) > maxForwardMessages {
+		return nil, errors.New("转发的消息过多")
+	}
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	template, err := forwardTarget(db, &user, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []model.Message
+	db.Where("id IN ?", req.MessageIds).Order("id").Find(&sources)
+	if len(sources) != len(uniqueIds(req.MessageIds)) {
+		return nil, errors.New("消息不存在或已撤回")
+	}
+	for i := range sources {
+		if sources[i].FromUserId != user.Id && !isRecipient(db, &sources[i], user.Id) {
+			return nil, errors.New("不是该消息所在会话的成员")
+		}
+		if !isForwardable(sources[i].ContentType) {
+			return nil, errors.New("该类型的消息不能转发")
+		}
+	}
+
+	var forwarded []model.Message
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if req.Merge {
+			record := *template
+			record.ContentType = constant.CHAT_RECORD
+			record.Content = recordTitle(tx, sources)
+			if err := insertMessage(tx, &record); err != nil {
+				return err
+			}
+			forwarded = append(forwarded, record)
+			return saveRecords(tx, record.ID, sources)
+		}
+
+		for _, source := range sources {
+			message := *template
+			message.Content = source.Content
+			message.ContentType = source.ContentType
+			message.Url = source.Url
+			message.Pic = source.Pic
+			message.ForwardFrom = originalSender(&source)
+			if err := insertMessage(tx, &message); err != nil {
+				return err
+			}
+			forwarded = append(forwarded, message)
+			// 逐条转发聊天记录时，新的聊天记录包含与原记录相同的消息
+			if source.ContentType == constant.CHAT_RECORD {
+				if err := tx.Exec("INSERT INTO message_records (created_at, record_id, message_id) "+
+					"SELECT NOW(), ?, message_id FROM message_records WHERE record_id = ? ORDER BY id", message.ID, source.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int32, 0, len(forwarded))
+	for i := range forwarded {
+		SearchService.indexMessage(&forwarded[i])
+		ids = append(ids, forwarded[i].ID)
+	}
+	messages := make([]response.MessageResponse, 0, len(ids))
+	messageQuery(db).Select(messageColumns).Where("m.id IN ?", ids).Order("m.id").Scan(&messages)
+	prepareMessages(messages)
+	return messages, nil
+}
+
+// GetRecords
+//
+//	@Description: 展开聊天记录，按转发时的顺序返回其中的原消息
+//	@Description: 聊天记录所在会话的成员可以查看，聊天记录被再次合并转发时，能查看外层记录的用户也可以查看
+//	@receiver f
+//	@param userUuid 当前登录用户
+//	@param recordId 聊天记录消息id
+//	@return []response.MessageResponse
+//	@return error
+func (f *forwardService) GetRecords(userUuid string, recordId int64) ([]response.MessageResponse, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	var record model.Message
+	db.Find(&record, "id = ?", recordId)
+	if NULL_ID == record.ID || record.ContentType != constant.CHAT_RECORD {
+		return nil, errors.New("聊天记录不存在")
+	}
+	if !canViewRecord(db, &record, user.Id) {
+		return nil, errors.New("没有权限查看该聊天记录")
+	}
+
+	messages := make([]response.MessageResponse, 0)
+	messageQuery(db).Select(messageColumns).Joins("JOIN message_records AS mr ON mr.message_id = m.id").
+		Where("mr.record_id = ?", record.ID).Order("mr.id").Scan(&messages)
+	prepareMessages(messages)
+	return messages, nil
+}
+
+// forwardTarget 校验转发的目标，返回填好接收方和会话的消息模板
+func forwardTarget(db *gorm.DB, user *model.User, req request.ForwardRequest) (*model.Message, error) {
+	message := &model.Message{
+		FromUserId:     user.Id,
+		MessageType:    int16(req.MessageType),
+		ConversationId: util.ConversationId(req.MessageType, user.Uuid, req.To),
+	}
+	switch req.MessageType {
+	case constant.MESSAGE_TYPE_USER:
+		var toUser model.User
+		db.Find(&toUser, "uuid = ?", req.To)
+		if NULL_ID == toUser.Id {
+			return nil, errors.New("用户不存在")
+		}
+		message.ToUserId = toUser.Id
+	case constant.MESSAGE_TYPE_GROUP:
+		var group model.Group
+		db.Find(&group, "uuid = ?", req.To)
+		if NULL_ID == group.ID {
+			return nil, errors.New("群组不存在")
+		}
+		if err := checkSend(db, &group, user.Id); err != nil {
+			return nil, err
+		}
+		message.ToUserId = group.ID
+	default:
+		return nil, errors.New("不支持的消息类型")
+	}
+	return message, nil
+}
+
+// isForwardable 保存过的普通消息和聊天记录可以转发，语音、视频通话不保存也不能转发
+func isForwardable(contentType int16) bool {
+	return (contentType >= constant.TEXT && contentType <= constant.VIDEO) || contentType == constant.CHAT_RECORD
+}
+
+// originalSender 消息的原发送者，转发的消息再次转发时仍然保留最初的发送者
+func originalSender(message *model.Message) int32 {
+	if message.ForwardFrom > 0 {
+		return message.ForwardFrom
+	}
+	return message.FromUserId
+}
+
+// recordTitle 聊天记录的标题，列出原消息的发送者
+func recordTitle(db *gorm.DB, sources []model.Message) string {
+	var senderIds []int32
+	seen := make(map[int32]bool)
+	for i := range sources {
+		sender := originalSender(&sources[i])
+		if !seen[sender] {
+			seen[sender] = true
+			senderIds = append(senderIds, sender)
+		}
+	}
+	var names []string
+	for _, senderId := range senderIds {
+		if len(names) == recordTitleNames {
+			break
+		}
+		var sender model.User
+		db.Find(&sender, "id = ?", senderId)
+		names = append(names, sender.Username)
+	}
+	title := strings.Join(names, "和")
+	if len(senderIds) > recordTitleNames {
+		title += "等"
+	}
+	return title + "的聊天记录"
+}
+
+// saveRecords 记录聊天记录中包含的原消息
+func saveRecords(tx *gorm.DB, recordId int32, sources []model.Message) error {
+	records := make([]model.MessageRecord, 0, len(sources))
+	for i := range sources {
+		records = append(records, model.MessageRecord{RecordId: recordId, MessageId: sources[i].ID})
+	}
+	return tx.Create(&records).Error
+}
+
+// canViewRecord 用户是否可以查看聊天记录：是记录所在会话的成员，或者可以查看包含它的外层聊天记录
+func canViewRecord(db *gorm.DB, record *model.Message, userId int32) bool {
+	frontier := []model.Message{*record}
+	for depth := 0; depth < maxRecordDepth && len(frontier) > 0; depth++ {
+		ids := make([]int32, 0, len(frontier))
+		for i := range frontier {
+			if frontier[i].FromUserId == userId || isRecipient(db, &frontier[i], userId) {
+				return true
+			}
+			ids = append(ids, frontier[i].ID)
+		}
+		var parents []model.Message
+		db.Joins("JOIN message_records AS mr ON mr.record_id = messages.id").
+			Where("mr.message_id IN ?", ids).Find(&parents)
+		frontier = parents
+	}
+	return false
+}
+
+// uniqueIds 去重后的id
+func uniqueIds(ids []int64) map[int64]bool {
+	unique := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return unique
+}
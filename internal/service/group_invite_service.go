@@ -0,0 +1,205 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateInvite
+//
+//	@Description: 创建群邀请链接，需要是群主或管理员，可以设置有效时长和最多使用次数
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param req
+//	@return *response.GroupInviteResponse
+//	@return error
+func (g *groupService) CreateInvite(userUuid string, groupUuid string, req request.InviteRequest) (*response.GroupInviteResponse, error) {
+	if req.ExpireIn < 0 || req.MaxUses < 0 {
+		return nil, errors.New("有效时长和使用次数不能为负数")
+	}
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_LINK); err != nil {
+		return nil, err
+	}
+
+	invite := model.GroupInvite{
+		Token:     strings.ReplaceAll(uuid.NewString(), "-", ""),
+		GroupId:   group.ID,
+		CreatorId: user.Id,
+		MaxUses:   req.MaxUses,
+	}
+	if req.ExpireIn > 0 {
+		invite.ExpireAt = time.Now().Add(time.Duration(req.ExpireIn) * time.Second).UnixMilli()
+	}
+	if err = db.Create(&invite).Error; err != nil {
+		return nil, err
+	}
+	return &response.GroupInviteResponse{
+		Token:     invite.Token,
+		Creator:   user.Username,
+		ExpireAt:  invite.ExpireAt,
+		MaxUses:   invite.MaxUses,
+		CreatedAt: invite.CreatedAt,
+	}, nil
+}
+
+// GetInvites
+//
+//	@Description: 获取群的邀请链接，包括已过期和已撤销的，按创建时间倒序
+//	@receiver g
+//	@param userUuid 当前登录用户，需要是群主或管理员
+//	@param groupUuid
+//	@return []response.GroupInviteResponse
+//	@return error
+func (g *groupService) GetInvites(userUuid string, groupUuid string) ([]response.GroupInviteResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_LINK); err != nil {
+		return nil, err
+	}
+
+	invites := make([]response.GroupInviteResponse, 0)
+	db.Raw("SELECT i.token, u.username AS creator, i.expire_at, i.max_uses, i.uses, i.revoked, i.created_at FROM group_invites AS i "+
+		"LEFT JOIN users AS u ON u.id = i.creator_id WHERE i.group_id = ? ORDER BY i.id DESC",
+		group.ID).Scan(&invites)
+	return invites, nil
+}
+
+// RevokeInvite
+//
+//	@Description: 撤销群邀请链接，撤销后不能再用于入群
+//	@receiver g
+//	@param userUuid 当前登录用户，需要是群主或管理员
+//	@param groupUuid
+//	@param token
+//	@return error
+func (g *groupService) RevokeInvite(userUuid string, groupUuid string, token string) error {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_LINK); err != nil {
+		return err
+	}
+	result := db.Model(&model.GroupInvite{}).Where("group_id = ? AND token = ?", group.ID, token).Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("邀请链接不存在")
+	}
+	return nil
+}
+
+// RedeemInvite
+//
+//	@Description: 使用邀请链接加入群组。使用次数在条件更新中增加，并发使用时不会超过最多使用次数，
+//	@Description: 入群失败(如已在群中、被禁止加入)时不计入使用次数。入群成功后记录通过哪个链接加入
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param token
+//	@return *response.GroupResponse 加入的群组
+//	@return error
+func (g *groupService) RedeemInvite(userUuid string, token string) (*response.GroupResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	var invite model.GroupInvite
+	db.Where("token = ?", token).Limit(1).Find(&invite)
+	if invite.ID <= 0 {
+		return nil, errors.New("邀请链接不存在")
+	}
+	var group model.Group
+	db.Find(&group, invite.GroupId)
+	if group.ID <= 0 {
+		return nil, errors.New("群组不存在")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.GroupInvite{}).
+			Where("id = ? AND revoked = ? AND (max_uses = 0 OR uses < max_uses) AND (expire_at = 0 OR expire_at > ?)",
+				invite.ID, false, time.Now().UnixMilli()).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("邀请链接已失效")
+		}
+		if err := addMember(tx, &group, &user); err != nil {
+			return err
+		}
+		return tx.Create(&model.GroupInviteUse{InviteId: invite.ID, GroupId: group.ID, UserId: user.Id}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response.GroupResponse{
+		Uuid:      group.Uuid,
+		GroupId:   group.ID,
+		CreatedAt: group.CreatedAt,
+		Name:      group.Name,
+		Notice:    group.Notice,
+		AllMuted:  group.AllMuted,
+	}, nil
+}
+
+// GetInviteUses
+//
+//	@Description: 查询通过邀请链接入群的记录，按入群时间倒序
+//	@receiver g
+//	@param userUuid 当前登录用户，需要是群主或管理员
+//	@param groupUuid
+//	@param token 不为空时只查询该链接的记录
+//	@return []response.GroupInviteUseResponse
+//	@return error
+func (g *groupService) GetInviteUses(userUuid string, groupUuid string, token string) ([]response.GroupInviteUseResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_LINK); err != nil {
+		return nil, err
+	}
+
+	query := db.Table("group_invite_uses AS iu").
+		Select("i.token, c.username AS creator, u.uuid, u.username, u.avatar, iu.created_at").
+		Joins("JOIN group_invites AS i ON i.id = iu.invite_id").
+		Joins("JOIN users AS u ON u.id = iu.user_id").
+		Joins("LEFT JOIN users AS c ON c.id = i.creator_id").
+		Where("iu.group_id = ?", group.ID)
+	if token != "" {
+		query = query.Where("i.token = ?", token)
+	}
+	uses := make([]response.GroupInviteUseResponse, 0)
+	query.Order("iu.id DESC").Scan(&uses)
+	return uses, nil
+}
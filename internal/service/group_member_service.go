@@ -0,0 +1,194 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaveGroup
+//
+//	@Description: 退出群聊，群主需要先转让群主才能退出；退出后向其余成员发送系统消息
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@return *response.MessageResponse 系统消息
+//	@return error
+func (g *groupService) LeaveGroup(userUuid string, groupUuid string) (*response.MessageResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	role, ok := memberRole(db, group, user.Id)
+	if !ok {
+		return nil, errors.New("不是该群成员")
+	}
+	if role == constant.GROUP_ROLE_OWNER {
+		return nil, errors.New("群主需要先转让群主才能退出")
+	}
+	return removeMember(db, group, user.Id, user.Id, user.Username+"退出了群聊")
+}
+
+// RemoveMember
+//
+//	@Description: 将成员移出群聊，ban为true时同时加入黑名单，禁止再次加入；需要有移出成员的权限
+//	@Description: 不在群中的用户也可以加入黑名单，此时不发送系统消息
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param memberUuid 被移出的用户
+//	@param ban
+//	@return *response.MessageResponse 系统消息，没有移出成员时为nil
+//	@return error
+func (g *groupService) RemoveMember(userUuid string, groupUuid string, memberUuid string, ban bool) (*response.MessageResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	var member model.User
+	db.Find(&member, "uuid = ?", memberUuid)
+	if NULL_ID == member.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	_, isMember := memberRole(db, group, member.Id)
+	if isMember {
+		err = checkManage(db, group, user.Id, member.Id, permission.GROUP_ACTION_KICK)
+	} else if ban {
+		_, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_KICK)
+	} else {
+		err = errors.New("对方不是该群成员")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ban {
+		groupBan := model.GroupBan{GroupId: group.ID, UserId: member.Id, OperatorId: user.Id}
+		if err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&groupBan).Error; err != nil {
+			return nil, err
+		}
+	}
+	if !isMember {
+		return nil, nil
+	}
+	content := user.Username + "将" + member.Username + "移出了群聊"
+	if ban {
+		content += "并禁止再次加入"
+	}
+	return removeMember(db, group, member.Id, user.Id, content)
+}
+
+// UnbanMember
+//
+//	@Description: 将用户移出黑名单，移出后可以再次加入群聊
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param memberUuid
+//	@return error
+func (g *groupService) UnbanMember(userUuid string, groupUuid string, memberUuid string) error {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_KICK); err != nil {
+		return err
+	}
+	var member model.User
+	db.Find(&member, "uuid = ?", memberUuid)
+	if NULL_ID == member.Id {
+		return errors.New("用户不存在")
+	}
+	return db.Where("group_id = ? AND user_id = ?", group.ID, member.Id).Delete(&model.GroupBan{}).Error
+}
+
+// GetBans
+//
+//	@Description: 获取群黑名单，需要有移出成员的权限
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@return []response.GroupBanResponse
+//	@return error
+func (g *groupService) GetBans(userUuid string, groupUuid string) ([]response.GroupBanResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_KICK); err != nil {
+		return nil, err
+	}
+
+	bans := make([]response.GroupBanResponse, 0)
+	db.Raw("SELECT u.uuid, u.username, u.avatar, o.username AS operator, b.created_at FROM group_bans AS b "+
+		"JOIN users AS u ON u.id = b.user_id LEFT JOIN users AS o ON o.id = b.operator_id WHERE b.group_id = ? ORDER BY b.id DESC",
+		group.ID).Scan(&bans)
+	return bans, nil
+}
+
+// removeMember
+//
+//	@Description: 在同一事务中保存系统消息并移除成员，成员记录软删除，同时删除该成员的群聊会话
+//	@Description: 系统消息先于移除保存，移出的成员不会再收到该群后续的消息
+//	@param db
+//	@param group
+//	@param userId 被移除的成员
+//	@param operatorId 操作者，作为系统消息的发送者
+//	@param content 系统消息内容
+//	@return *response.MessageResponse 系统消息
+//	@return error
+func removeMember(db *gorm.DB, group *model.Group, userId int32, operatorId int32, content string) (*response.MessageResponse, error) {
+	var message *model.Message
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if message, err = saveSystemMessage(tx, group, operatorId, content); err != nil {
+			return err
+		}
+		if err = tx.Where("group_id = ? AND user_id = ?", group.ID, userId).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND conversation_id = ?", userId, group.Uuid).Delete(&model.Conversation{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadMessage(db, message.ID), nil
+}
+
+// saveSystemMessage 在群聊中保存一条系统消息，需要在事务中执行
+func saveSystemMessage(tx *gorm.DB, group *model.Group, operatorId int32, content string) (*model.Message, error) {
+	message := &model.Message{
+		FromUserId:     operatorId,
+		ToUserId:       group.ID,
+		Content:        content,
+		ContentType:    constant.SYSTEM,
+		MessageType:    constant.MESSAGE_TYPE_GROUP,
+		ConversationId: group.Uuid,
+	}
+	return message, insertMessage(tx, message)
+}
+
+// loadMessage 按聊天记录的格式查询一条消息
+func loadMessage(db *gorm.DB, msgId int32) *response.MessageResponse {
+	var message response.MessageResponse
+	messageQuery(db).Select(messageColumns).Where("m.id = ?", msgId).Scan(&message)
+	prepareMessage(&message)
+	return &message
+}
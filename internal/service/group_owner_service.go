@@ -0,0 +1,114 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TransferOwner
+//
+//	@Description: 将群主转让给群中的其他成员，原群主成为普通成员；转让后向群成员发送系统消息
+//	@receiver g
+//	@param userUuid 当前登录用户，需要是群主
+//	@param groupUuid
+//	@param ownerUuid 新群主
+//	@return *response.MessageResponse 系统消息
+//	@return error
+func (g *groupService) TransferOwner(userUuid string, groupUuid string, ownerUuid string) (*response.MessageResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_TRANSFER); err != nil {
+		return nil, err
+	}
+	var owner model.User
+	db.Find(&owner, "uuid = ?", ownerUuid)
+	if NULL_ID == owner.Id {
+		return nil, errors.New("用户不存在")
+	}
+	if owner.Id == user.Id {
+		return nil, errors.New("不能转让给自己")
+	}
+	if _, ok := memberRole(db, group, owner.Id); !ok {
+		return nil, errors.New("对方不是该群成员")
+	}
+
+	var message *model.Message
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Update("user_id", owner.Id).Error; err != nil {
+			return err
+		}
+		if err := setRole(tx, group.ID, owner.Id, constant.GROUP_ROLE_OWNER); err != nil {
+			return err
+		}
+		if err := setRole(tx, group.ID, user.Id, constant.GROUP_ROLE_MEMBER); err != nil {
+			return err
+		}
+		message, err = saveSystemMessage(tx, group, user.Id, user.Username+"将群主转让给了"+owner.Username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loadMessage(db, message.ID), nil
+}
+
+// DissolveGroup
+//
+//	@Description: 解散群聊，群和全部成员记录软删除，之后不能再发送消息，解散时的成员仍然可以查看聊天记录
+//	@Description: 群和成员记录使用同一个删除时间，据此判断用户是否为解散时的成员
+//	@receiver g
+//	@param userUuid 当前登录用户，需要是群主
+//	@param groupUuid
+//	@return *response.MessageResponse 系统消息
+//	@return []string 解散时的成员uuid，用于通知
+//	@return error
+func (g *groupService) DissolveGroup(userUuid string, groupUuid string) (*response.MessageResponse, []string, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_DISSOLVE); err != nil {
+		return nil, nil, err
+	}
+	var members []string
+	for _, member := range g.GetUserIdByGroupUuid(group.Uuid) {
+		members = append(members, member.Uuid)
+	}
+
+	deletedAt := time.Now().Unix()
+	var message *model.Message
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if message, err = saveSystemMessage(tx, group, user.Id, user.Username+"解散了群聊"); err != nil {
+			return err
+		}
+		if err = tx.Model(&model.GroupMember{}).Where("group_id = ?", group.ID).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(group).Update("deleted_at", deletedAt).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return loadMessage(db, message.ID), members, nil
+}
+
+// setRole 设置群成员的角色
+func setRole(db *gorm.DB, groupId int32, userId int32, role int16) error {
+	return db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", groupId, userId).Update("role", role).Error
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// memberRole
+//
+//	@Description: 用户在群中的角色，群主以groups.user_id为准，功能上线前创建的群没有在成员表中记录群主角色
+//	@param db
+//	@param group
+//	@param userId
+//	@return int16 角色
+//	@return bool 是否为群成员
+func memberRole(db *gorm.DB, group *model.Group, userId int32) (int16, bool) {
+	var member model.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, userId).Limit(1).Find(&member)
+	if member.ID <= 0 {
+		return 0, false
+	}
+	return permission.Role(group, &member), true
+}
+
+// checkPermission 校验用户是否可以在群中执行该操作，返回用户的角色
+func checkPermission(db *gorm.DB, group *model.Group, userId int32, action string) (int16, error) {
+	role, ok := memberRole(db, group, userId)
+	if !ok {
+		return 0, errors.New("不是该群成员")
+	}
+	return role, permission.Check(role, action)
+}
+
+// checkManage 校验用户是否可以对群中的另一个成员执行操作(移出、禁言等)，只能管理角色比自己低的成员
+func checkManage(db *gorm.DB, group *model.Group, userId int32, targetId int32, action string) error {
+	role, err := checkPermission(db, group, userId, action)
+	if err != nil {
+		return err
+	}
+	targetRole, ok := memberRole(db, group, targetId)
+	if !ok {
+		return errors.New("对方不是该群成员")
+	}
+	return permission.CheckManage(role, targetRole)
+}
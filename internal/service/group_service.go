@@ -1,13 +1,22 @@
 package service
 
 import (
+	"chat-room/config"
 	"chat-room/internal/dao/pool"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
 	"chat-room/pkg/common/response"
 	"chat-room/pkg/errors"
 
 	"chat-room/internal/model"
+	"chat-room/internal/permission"
+
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type groupService struct {
@@ -15,6 +24,16 @@ type groupService struct {
 
 var GroupService = new(groupService)
 
+// 群组相关的表结构只需要迁移一次
+var migrateGroupOnce sync.Once
+
+// migrateGroupTables 迁移群组相关的表结构
+func migrateGroupTables(db *gorm.DB) {
+	migrateGroupOnce.Do(func() {
+		_ = db.AutoMigrate(&model.Group{}, &model.GroupMember{}, &model.GroupBan{}, &model.GroupInvite{}, &model.GroupInviteUse{})
+	})
+}
+
 // GetGroups
 //  @Description: 获取群聊列表逻辑
 //  @receiver g
@@ -24,10 +43,7 @@ var GroupService = new(groupService)
 func (g *groupService) GetGroups(uuid string) ([]response.GroupResponse, error) {
 	db := pool.GetDB()
 
-	migrate := &model.Group{}
-	_ = pool.GetDB().AutoMigrate(&migrate)
-	migrate2 := &model.GroupMember{}
-	_ = pool.GetDB().AutoMigrate(&migrate2)
+	migrateGroupTables(db)
 
 	var queryUser *model.User
 	db.First(&queryUser, "uuid = ?", uuid)
@@ -38,8 +54,8 @@ func (g *groupService) GetGroups(uuid string) ([]response.GroupResponse, error)
 
 	var groups []response.GroupResponse
 
-	db.Raw("SELECT g.id AS group_id, g.uuid, g.created_at, g.name, g.notice FROM group_members AS gm LEFT JOIN `groups` AS g ON gm.group_id = g.id WHERE gm.user_id = ?",
-		queryUser.Id).Scan(&groups)
+	db.Raw("SELECT g.id AS group_id, g.uuid, g.created_at, g.name, g.notice, g.all_muted, IF(g.user_id = gm.user_id, ?, gm.role) AS role FROM group_members AS gm LEFT JOIN `groups` AS g ON gm.group_id = g.id WHERE gm.user_id = ? AND gm.deleted_at = 0",
+		constant.GROUP_ROLE_OWNER, queryUser.Id).Scan(&groups)
 
 	return groups, nil
 }
@@ -69,8 +85,10 @@ func (g *groupService) SaveGroup(userUuid string, group model.Group) {
 		GroupId:  group.ID,
 		Nickname: fromUser.Username,
 		Mute:     0,
+		Role:     constant.GROUP_ROLE_OWNER,
 	}
 	db.Save(&groupMember)
+	_ = joinConversation(db, fromUser.Id, &group)
 }
 
 // GetUserIdByGroupUuid
@@ -87,40 +105,300 @@ func (g *groupService) GetUserIdByGroupUuid(groupUuid string) []model.User {
 	}
 
 	var users []model.User
-	db.Raw("SELECT u.uuid, u.avatar, u.username FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id JOIN users AS u ON u.id = gm.user_id WHERE g.id = ?",
+	db.Raw("SELECT u.id, u.uuid, u.avatar, u.username FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id JOIN users AS u ON u.id = gm.user_id WHERE g.id = ? AND gm.deleted_at = 0",
 		group.ID).Scan(&users)
 	return users
 }
 
+// JoinGroup
+//
+//	@Description: 通过群uuid直接加入群聊，兼容旧客户端，需要在配置中开启[group] openJoin，否则只能通过邀请链接或成员邀请入群
+//	@receiver g
+//	@param groupUuid
+//	@param userUuid
+//	@return error
 func (g *groupService) JoinGroup(groupUuid, userUuid string) error {
-	var user model.User
+	if !config.GetConfig().Group.OpenJoin {
+		return errors.New("该服务不允许直接加入群聊，请通过邀请链接加入")
+	}
+	db := pool.GetDB()
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	return addMember(db, group, user)
+}
+
+// addMember 将用户加入群组并创建群聊会话，被禁止加入的用户不能入群
+// 同一群组的加入操作通过锁定群组记录串行执行，避免并发入群时重复添加成员
+func addMember(db *gorm.DB, group *model.Group, user *model.User) error {
+	// 建表语句会隐式提交事务，需要在事务开始前完成迁移
+	migrateGroupTables(db)
+	migrateMessageTables(db)
+	return db.Transaction(func(tx *gorm.DB) error {
+		var locked model.Group
+		tx.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&locked, group.ID)
+		if NULL_ID == locked.ID {
+			return errors.New("群组不存在")
+		}
+		var banCount int64
+		tx.Model(&model.GroupBan{}).Where("group_id = ? AND user_id = ?", group.ID, user.Id).Count(&banCount)
+		if banCount > 0 {
+			return errors.New("已被禁止加入该群组")
+		}
+		var groupMember model.GroupMember
+		tx.Where("user_id = ? and group_id = ?", user.Id, group.ID).Limit(1).Find(&groupMember)
+		if groupMember.ID > 0 {
+			return errors.New("已经加入该群组")
+		}
+		nickname := user.Nickname
+		if nickname == "" {
+			nickname = user.Username
+		}
+		groupMemberInsert := model.GroupMember{
+			UserId:   user.Id,
+			GroupId:  group.ID,
+			Nickname: nickname,
+			Mute:     0,
+		}
+		if err := tx.Save(&groupMemberInsert).Error; err != nil {
+			return err
+		}
+
+		return joinConversation(tx, user.Id, group)
+	})
+}
+
+// GetMembers
+//
+//	@Description: 获取群成员及其角色，只有群成员可以查看，已退出或被移出的不能查看
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@return []response.GroupMemberResponse
+//	@return error
+func (g *groupService) GetMembers(userUuid string, groupUuid string) ([]response.GroupMemberResponse, error) {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := memberRole(db, group, user.Id); !ok {
+		return nil, errors.New("不是该群成员")
+	}
+	members := make([]response.GroupMemberResponse, 0)
+	// 禁言到期后不再显示为禁言
+	db.Raw("SELECT u.uuid, u.username, u.avatar, gm.nickname, gm.mute = 1 AND (gm.mute_until = 0 OR gm.mute_until > ?) AS mute, gm.mute_until, "+
+		"IF(gm.user_id = ?, ?, gm.role) AS role "+
+		"FROM group_members AS gm JOIN users AS u ON u.id = gm.user_id WHERE gm.group_id = ? AND gm.deleted_at = 0 ORDER BY role DESC, gm.id",
+		time.Now().UnixMilli(), group.UserId, constant.GROUP_ROLE_OWNER, group.ID).Scan(&members)
+	return members, nil
+}
+
+// ModifyGroup
+//
+//	@Description: 修改群名称或群公告，未传的字段保持不变，需要有对应的权限
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param req
+//	@return error
+func (g *groupService) ModifyGroup(userUuid string, groupUuid string, req request.GroupRequest) error {
 	db := pool.GetDB()
-	db.First(&user, "uuid = ?", userUuid)
-	if user.Id <= 0 {
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_RENAME); err != nil {
+			return err
+		}
+		if *req.Name == "" {
+			return errors.New("群名称不能为空")
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Notice != nil {
+		if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_NOTICE); err != nil {
+			return err
+		}
+		updates["notice"] = *req.Notice
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return db.Model(group).Updates(updates).Error
+}
+
+// InviteMember
+//
+//	@Description: 邀请用户加入群组，需要有邀请权限
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param inviteeUuid 被邀请的用户
+//	@return error
+func (g *groupService) InviteMember(userUuid string, groupUuid string, inviteeUuid string) error {
+	db := pool.GetDB()
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_INVITE); err != nil {
+		return err
+	}
+	var invitee model.User
+	db.Find(&invitee, "uuid = ?", inviteeUuid)
+	if NULL_ID == invitee.Id {
 		return errors.New("用户不存在")
 	}
+	return addMember(db, group, &invitee)
+}
 
-	var group model.Group
-	db.First(&group, "uuid = ?", groupUuid)
-	if user.Id <= 0 {
-		return errors.New("群组不存在")
+// SetAdmin
+//
+//	@Description: 设置或取消管理员，只有群主可以操作
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param memberUuid 被设置的成员
+//	@param admin true为设为管理员，false为取消管理员
+//	@return error
+func (g *groupService) SetAdmin(userUuid string, groupUuid string, memberUuid string, admin bool) error {
+	db := pool.GetDB()
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
 	}
-	var groupMember model.GroupMember
-	db.First(&groupMember, "user_id = ? and group_id = ?", user.Id, group.ID)
-	if groupMember.ID > 0 {
-		return errors.New("已经加入该群组")
+	var member model.User
+	db.Find(&member, "uuid = ?", memberUuid)
+	if NULL_ID == member.Id {
+		return errors.New("用户不存在")
 	}
-	nickname := user.Nickname
-	if nickname == "" {
-		nickname = user.Username
+	if err = checkManage(db, group, user.Id, member.Id, permission.GROUP_ACTION_PROMOTE); err != nil {
+		return err
 	}
-	groupMemberInsert := model.GroupMember{
-		UserId:   user.Id,
-		GroupId:  group.ID,
-		Nickname: nickname,
-		Mute:     0,
+
+	role := constant.GROUP_ROLE_MEMBER
+	if admin {
+		role = constant.GROUP_ROLE_ADMIN
+	}
+	return db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, member.Id).Update("role", role).Error
+}
+
+// MuteMember
+//
+//	@Description: 禁言或解除禁言群成员，需要有禁言权限，且只能禁言角色比自己低的成员
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param memberUuid 被禁言的成员
+//	@param mute true为禁言，false为解除禁言
+//	@param duration 禁言时长，单位秒，0为永久禁言
+//	@return error
+func (g *groupService) MuteMember(userUuid string, groupUuid string, memberUuid string, mute bool, duration int64) error {
+	if duration < 0 {
+		return errors.New("禁言时长错误")
+	}
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	var member model.User
+	db.Find(&member, "uuid = ?", memberUuid)
+	if NULL_ID == member.Id {
+		return errors.New("用户不存在")
+	}
+	if err = checkManage(db, group, user.Id, member.Id, permission.GROUP_ACTION_MUTE); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"mute": 0, "mute_until": 0}
+	if mute {
+		updates["mute"] = 1
+		if duration > 0 {
+			updates["mute_until"] = time.Now().Add(time.Duration(duration) * time.Second).UnixMilli()
+		}
+	}
+	return db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", group.ID, member.Id).Updates(updates).Error
+}
+
+// SetAllMuted
+//
+//	@Description: 开启或关闭全员禁言，开启后只有群主和管理员可以发言
+//	@receiver g
+//	@param userUuid 当前登录用户
+//	@param groupUuid
+//	@param muted
+//	@return error
+func (g *groupService) SetAllMuted(userUuid string, groupUuid string, muted bool) error {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	if _, err = checkPermission(db, group, user.Id, permission.GROUP_ACTION_MUTE); err != nil {
+		return err
+	}
+	return db.Model(group).Update("all_muted", muted).Error
+}
+
+// CheckSend
+//
+//	@Description: 校验用户是否可以在群中发言：需要是群成员，没有被禁言，全员禁言时只有群主和管理员可以发言
+//	@receiver g
+//	@param userUuid
+//	@param groupUuid
+//	@return error
+func (g *groupService) CheckSend(userUuid string, groupUuid string) error {
+	db := pool.GetDB()
+	migrateGroupTables(db)
+	user, group, err := findUserAndGroup(db, userUuid, groupUuid)
+	if err != nil {
+		return err
+	}
+	return checkSend(db, group, user.Id)
+}
+
+func checkSend(db *gorm.DB, group *model.Group, userId int32) error {
+	var member model.GroupMember
+	db.Where("group_id = ? AND user_id = ?", group.ID, userId).Limit(1).Find(&member)
+	if member.ID <= 0 {
+		return errors.New("不是该群成员")
+	}
+	return permission.CheckSend(group, &member, time.Now())
+}
+
+// checkMessageSend 群聊消息上的编辑、表情回应、置顶等操作同样对群成员可见，和发言一样受禁言限制
+func checkMessageSend(db *gorm.DB, message *model.Message, userId int32) error {
+	if message.MessageType != constant.MESSAGE_TYPE_GROUP {
+		return nil
 	}
-	db.Save(&groupMemberInsert)
+	var group model.Group
+	db.Find(&group, "id = ?", message.ToUserId)
+	if NULL_ID == group.ID {
+		return errors.New("群组不存在")
+	}
+	return checkSend(db, &group, userId)
+}
 
-	return nil
+// findUserAndGroup 查询当前登录用户和群组
+func findUserAndGroup(db *gorm.DB, userUuid string, groupUuid string) (*model.User, *model.Group, error) {
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, nil, errors.New("用户不存在")
+	}
+	var group model.Group
+	db.Find(&group, "uuid = ?", groupUuid)
+	if NULL_ID == group.ID {
+		return nil, nil, errors.New("群组不存在")
+	}
+	return &user, &group, nil
 }
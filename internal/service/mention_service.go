@@ -0,0 +1,110 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type mentionService struct {
+}
+
+var MentionService = new(mentionService)
+
+// GetMentions
+//
+//	@Description: @我的消息，按消息id游标分页，传入群uuid时只查询该群中的消息
+//	@Description: 已撤回的消息不再提醒
+//	@receiver m
+//	@param userUuid 当前登录用户
+//	@param message 分页参数，uuid为可选的群uuid
+//	@return *response.MessagePage
+//	@return error
+func (m *mentionService) GetMentions(userUuid string, message request.MessageRequest) (*response.MessagePage, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	query := messageQuery(db).Select(messageColumns).
+		Joins("JOIN message_mentions AS mm ON mm.message_id = m.id").
+		Where("mm.user_id = ? AND m.deleted_at = 0", user.Id)
+	if message.Uuid != "" {
+		query = query.Where("mm.conversation_id = ?", message.Uuid)
+	}
+	return pageMessages(query, userUuid, message), nil
+}
+
+// resolveMentions
+//
+//	@Description: 校验群聊消息中@的成员，并将规范化后的@列表记录到消息上
+//	@Description: 被@的用户必须是群成员；单聊消息不支持@，忽略客户端传入的@列表
+//	@param message 待保存的消息
+//	@param mentions 客户端传入的成员uuid，all为@所有人
+//	@return []int32 需要提醒的用户id，不包含发送者自己
+//	@return error
+func resolveMentions(message *model.Message, mentions []string) ([]int32, error) {
+	if len(mentions) == 0 || message.MessageType != constant.MESSAGE_TYPE_GROUP {
+		return nil, nil
+	}
+
+	members := make(map[string]int32)
+	for _, member := range GroupService.GetUserIdByGroupUuid(message.ConversationId) {
+		members[member.Uuid] = member.Id
+	}
+
+	var all bool
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, mention := range mentions {
+		if seen[mention] {
+			continue
+		}
+		seen[mention] = true
+		if mention == constant.MENTION_ALL {
+			all = true
+		} else if _, ok := members[mention]; !ok {
+			return nil, errors.New("@的用户不是群成员")
+		}
+		normalized = append(normalized, mention)
+	}
+	message.Mentions = strings.Join(normalized, ",")
+
+	var userIds []int32
+	for memberUuid, id := range members {
+		if id == message.FromUserId {
+			continue
+		}
+		if all || seen[memberUuid] {
+			userIds = append(userIds, id)
+		}
+	}
+	return userIds, nil
+}
+
+// saveMentions 记录消息中被@的成员，与保存消息在同一事务中执行
+func saveMentions(tx *gorm.DB, message *model.Message, userIds []int32) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	mentions := make([]model.MessageMention, 0, len(userIds))
+	for _, userId := range userIds {
+		mentions = append(mentions, model.MessageMention{
+			MessageId:      message.ID,
+			UserId:         userId,
+			ConversationId: message.ConversationId,
+			Seq:            message.Seq,
+		})
+	}
+	return tx.Create(&mentions).Error
+}
@@ -1,42 +1,77 @@
 package service
 
 import (
+	"chat-room/config"
 	"chat-room/internal/dao/pool"
 	"chat-room/pkg/common/constant"
 	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
 	"chat-room/pkg/errors"
 	"chat-room/pkg/global/log"
 	"chat-room/pkg/protocol"
+	"sync"
+	"time"
 
 	"chat-room/internal/model"
 	"chat-room/pkg/common/request"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const NULL_ID int32 = 0
 
+// 消息相关的表结构只需要迁移一次，不必每次保存消息时都检查
+var migrateMessageOnce sync.Once
+
+// migrateMessageTables 迁移消息相关的表结构，并补充已有数据缺少的会话记录
+func migrateMessageTables(db *gorm.DB) {
+	migrateMessageOnce.Do(func() {
+		_ = db.AutoMigrate(&model.Message{}, &model.ConversationSequence{}, &model.MessageReceipt{}, &model.Conversation{},
+			&model.MessageDeletion{}, &model.MessageEdit{}, &model.MessageReaction{}, &model.MessageMention{}, &model.MessageRecord{}, &model.MessagePin{})
+		// 会话列表上线前加入的群没有会话记录，补充记录并视为已读到当前位置，已有记录的不受影响
+		db.Exec("INSERT IGNORE INTO conversations (created_at, updated_at, user_id, conversation_id, message_type, peer_id, read_seq) "+
+			"SELECT NOW(), NOW(), gm.user_id, g.uuid, ?, g.id, COALESCE(s.seq, 0) FROM group_members AS gm JOIN `groups` AS g ON g.id = gm.group_id "+
+			"LEFT JOIN conversation_sequences AS s ON s.conversation_id = g.uuid WHERE gm.deleted_at = 0 AND g.deleted_at = 0",
+			constant.MESSAGE_TYPE_GROUP)
+	})
+}
+
 type messageService struct {
 }
 
 var MessageService = new(messageService)
 
+// 聊天记录查询的字段，需要配合messageQuery的关联查询使用，单聊额外查询接收者的用户名
+const messageColumns = "m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.message_type, m.url, m.client_msg_id, m.conversation_id, m.seq, m.created_at, m.deleted_at > 0 AS recalled, m.edited_at, m.edited_at > 0 AS edited, m.mentions, " +
+	"fw.uuid AS forward_from, fw.username AS forward_from_username, " +
+	"m.reply_to, m.thread_root, (SELECT COUNT(*) FROM messages AS t WHERE t.thread_root = m.id AND t.deleted_at = 0) AS reply_count, " +
+	"IF(rm.deleted_at > 0, '', rm.content) AS quote, rm.content_type AS quote_content_type, ru.username AS quote_from, " +
+	"(SELECT COUNT(*) FROM message_receipts AS r WHERE r.message_id = m.id AND r.delivered_at > 0) AS delivered_count, " +
+	"(SELECT COUNT(*) FROM message_receipts AS r WHERE r.message_id = m.id AND r.read_at > 0) AS read_count, " +
+	"u.uuid AS from_uuid, u.username AS from_username, u.avatar"
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
 // GetMessages
-//  @Description: 根据payload请求，获取对应消息表中的聊天记录
+//  @Description: 根据payload请求，按游标分页获取对应消息表中的聊天记录
 //  @receiver m
+//  @param userUuid 当前登录用户
 //  @param message
-//  @return []response.MessageResponse
+//  @return *response.MessagePage
 //  @return error
-func (m *messageService) GetMessages(message request.MessageRequest) ([]response.MessageResponse, error) {
+func (m *messageService) GetMessages(userUuid string, message request.MessageRequest) (*response.MessagePage, error) {
 	db := pool.GetDB()
 
-	migrate := &model.Message{}
-	_ = pool.GetDB().AutoMigrate(&migrate)
+	migrateMessageTables(db)
 
 	// 单聊
 	if message.MessageType == constant.MESSAGE_TYPE_USER {
 		var queryUser *model.User
-		db.First(&queryUser, "uuid = ?", message.Uuid)
+		db.First(&queryUser, "uuid = ?", userUuid)
 		if queryUser.Id == NULL_ID {
 			return nil, errors.New("用户不存在")
 		}
@@ -47,51 +82,165 @@ func (m *messageService) GetMessages(message request.MessageRequest) ([]response
 			return nil, errors.New("用户不存在")
 		}
 
-		var messages []response.MessageResponse
 		/*
 			单聊逻辑就是把消息内容放到数据库中
 			点用户头像打开聊天窗口的时候就去表中查询对应记录返回给 app
 		*/
-		db.Raw("SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, u.username AS from_username, u.avatar, to_user.username AS to_username  FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id LEFT JOIN users AS to_user ON m.to_user_id = to_user.id WHERE from_user_id IN (?, ?) AND to_user_id IN (?, ?)",
-			queryUser.Id, friend.Id, queryUser.Id, friend.Id).Scan(&messages)
-		return messages, nil
+		query := messageQuery(db).Select(messageColumns+", to_user.username AS to_username").
+			Joins("LEFT JOIN users AS to_user ON m.to_user_id = to_user.id").
+			Where("m.message_type = ? AND ((m.from_user_id = ? AND m.to_user_id = ?) OR (m.from_user_id = ? AND m.to_user_id = ?))",
+				constant.MESSAGE_TYPE_USER, queryUser.Id, friend.Id, friend.Id, queryUser.Id)
+		return pageMessages(query, userUuid, message), nil
 	}
 
 	// 群聊
 	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
-		messages, err := fetchGroupMessage(db, message.Uuid)
-		if err != nil {
-			return nil, err
-		}
-
-		return messages, nil
+		return fetchGroupMessage(db, userUuid, message)
 	}
 
 	return nil, errors.New("不支持查询类型")
 }
 
-func fetchGroupMessage(db *gorm.DB, toUuid string) ([]response.MessageResponse, error) {
+func fetchGroupMessage(db *gorm.DB, userUuid string, message request.MessageRequest) (*response.MessagePage, error) {
+	// 已解散的群仍然可以查看聊天记录
 	var group model.Group
-	db.First(&group, "uuid = ?", toUuid)
+	db.Unscoped().Where("uuid = ?", message.Uuid).Limit(1).Find(&group)
 	if group.ID <= 0 {
 		return nil, errors.New("群组不存在")
 	}
 
-	var messages []response.MessageResponse
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id || !isParticipant(db, group.Uuid, &user) {
+		return nil, errors.New("不是该群成员")
+	}
+
+	query := messageQuery(db).Select(messageColumns).
+		Where("m.message_type = ? AND m.to_user_id = ?", constant.MESSAGE_TYPE_GROUP, group.ID)
+	return pageMessages(query, userUuid, message), nil
+}
+
+// GetThread
+//
+//	@Description: 获取话题，包含根消息以及按游标分页的回复；传入话题中的回复时返回它所在的话题
+//	@receiver m
+//	@param userUuid 当前登录用户，需要是话题所在会话的成员
+//	@param msgId
+//	@param message 分页参数
+//	@return *response.ThreadResponse
+//	@return error
+func (m *messageService) GetThread(userUuid string, msgId int64, message request.MessageRequest) (*response.ThreadResponse, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	var root response.MessageResponse
+	messageQuery(db).Select(messageColumns).Where("m.id = ?", msgId).Scan(&root)
+	if root.ThreadRoot > 0 {
+		messageQuery(db).Select(messageColumns).Where("m.id = ?", root.ThreadRoot).Scan(&root)
+	}
+	if NULL_ID == root.ID || root.ConversationId == "" {
+		return nil, errors.New("消息不存在")
+	}
+	if !isParticipant(db, root.ConversationId, &user) {
+		return nil, errors.New("不是该会话的成员")
+	}
+	roots := []response.MessageResponse{root}
+	prepareMessages(roots)
+	attachReactions(db, userUuid, roots)
+
+	query := messageQuery(db).Select(messageColumns).Where("m.thread_root = ?", root.ID)
+	return &response.ThreadResponse{
+		Root:    roots[0],
+		Replies: pageMessages(query, userUuid, message),
+	}, nil
+}
+
+// pageMessages
+//
+//	@Description: 按消息id游标分页，消息id自增，顺序即为发送时间的顺序
+//	@Description: 默认及指定before时从新往旧取，用于打开聊天窗口和向上翻页；指定after时从旧往新取
+//	@Description: 返回的一页消息总是按时间正序排列，nextCursor为继续翻页时需要传入的before/after
+//	@Description: 已撤回的消息以不含内容的占位返回，用户自己删除的消息不返回
+//	@param query 已带上会话条件的查询
+//	@param userUuid 当前登录用户
+//	@param message
+//	@return *response.MessagePage
+func pageMessages(query *gorm.DB, userUuid string, message request.MessageRequest) *response.MessagePage {
+	size := message.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	forward := message.After > 0
+	if forward {
+		query = query.Where("m.id > ?", message.After).Order("m.id ASC")
+	} else {
+		if message.Before > 0 {
+			query = query.Where("m.id < ?", message.Before)
+		}
+		query = query.Order("m.id DESC")
+	}
 
-	db.Raw("SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, u.username AS from_username, u.avatar FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id WHERE m.message_type = 2 AND m.to_user_id = ?",
-		group.ID).Scan(&messages)
+	messages := make([]response.MessageResponse, 0)
+	notDeleted(query, userUuid).Limit(size + 1).Scan(&messages)
+	prepareMessages(messages)
+	attachReactions(pool.GetDB(), userUuid, messages)
 
-	return messages, nil
+	page := &response.MessagePage{HasMore: len(messages) > size}
+	if page.HasMore {
+		messages = messages[:size]
+	}
+	if !forward {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+	if len(messages) > 0 {
+		if forward {
+			page.NextCursor = int64(messages[len(messages)-1].ID)
+		} else {
+			page.NextCursor = int64(messages[0].ID)
+		}
+	}
+	page.Messages = messages
+	return page
 }
 
-func (m *messageService) SaveMessage(message protocol.Message) {
+// SaveMessage
+//
+//	@Description: 保存消息，按发送者和客户端消息id幂等：客户端重发已保存过的消息时返回之前保存的记录
+//	@receiver m
+//	@param message
+//	@return *model.Message 保存后的消息，ID和CreatedAt由服务端生成
+//	@return bool 是否为重复消息
+//	@return error
+func (m *messageService) SaveMessage(message protocol.Message) (*model.Message, bool, error) {
 	db := pool.GetDB()
+	migrateMessageTables(db)
+
 	var fromUser model.User
 	db.Find(&fromUser, "uuid = ?", message.From)
 	if NULL_ID == fromUser.Id {
 		log.Logger.Error("SaveMessage not find from user", log.Any("SaveMessage not find from user", fromUser.Id))
-		return
+		return nil, false, errors.New("用户不存在")
+	}
+
+	if saved := findByClientMsgId(db, fromUser.Id, message.ClientMsgId); saved != nil {
+		return saved, true, nil
+	}
+	if message.ContentType == constant.CHAT_RECORD {
+		return nil, false, errors.New("聊天记录需要通过转发发送")
+	}
+	if message.ContentType == constant.SYSTEM {
+		return nil, false, errors.New("不能发送系统消息")
 	}
 
 	var toUserId int32 = 0
@@ -100,7 +249,7 @@ func (m *messageService) SaveMessage(message protocol.Message) {
 		var toUser model.User
 		db.Find(&toUser, "uuid = ?", message.To)
 		if NULL_ID == toUser.Id {
-			return
+			return nil, false, errors.New("用户不存在")
 		}
 		toUserId = toUser.Id
 	}
@@ -109,18 +258,444 @@ func (m *messageService) SaveMessage(message protocol.Message) {
 		var group model.Group
 		db.Find(&group, "uuid = ?", message.To)
 		if NULL_ID == group.ID {
-			return
+			return nil, false, errors.New("群组不存在")
 		}
 		toUserId = group.ID
 	}
 
 	saveMessage := model.Message{
-		FromUserId:  fromUser.Id,
-		ToUserId:    toUserId,
-		Content:     message.Content,
-		ContentType: int16(message.ContentType),
-		MessageType: int16(message.MessageType),
-		Url:         message.Url,
-	}
-	db.Save(&saveMessage)
+		FromUserId:     fromUser.Id,
+		ToUserId:       toUserId,
+		Content:        message.Content,
+		ContentType:    int16(message.ContentType),
+		MessageType:    int16(message.MessageType),
+		Url:            message.Url,
+		ClientMsgId:    message.ClientMsgId,
+		ConversationId: util.ConversationId(message.MessageType, message.From, message.To),
+	}
+	// 回复的消息必须在同一会话中，回复话题中的消息时归入同一个话题
+	if message.ReplyTo > 0 {
+		var replied model.Message
+		db.Find(&replied, "id = ?", message.ReplyTo)
+		if NULL_ID == replied.ID || replied.ConversationId != saveMessage.ConversationId {
+			return nil, false, errors.New("回复的消息不存在")
+		}
+		saveMessage.ReplyTo = replied.ID
+		saveMessage.ThreadRoot = replied.ThreadRoot
+		if saveMessage.ThreadRoot == 0 {
+			saveMessage.ThreadRoot = replied.ID
+		}
+	}
+	mentioned, err := resolveMentions(&saveMessage, message.Mentions)
+	if err != nil {
+		return nil, false, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := insertMessage(tx, &saveMessage); err != nil {
+			return err
+		}
+		return saveMentions(tx, &saveMessage, mentioned)
+	})
+	if err != nil {
+		// 同一条消息并发重发时唯一索引冲突，返回先保存的那条
+		if saved := findByClientMsgId(db, fromUser.Id, message.ClientMsgId); saved != nil {
+			return saved, true, nil
+		}
+		return nil, false, err
+	}
+	SearchService.indexMessage(&saveMessage)
+	return &saveMessage, false, nil
+}
+
+// insertMessage 分配会话内序号并保存消息，同时更新会话
+// 需要在事务中执行，保存失败时序号回滚，保证序号连续
+func insertMessage(tx *gorm.DB, message *model.Message) error {
+	seq, err := nextSeq(tx, message.ConversationId)
+	if err != nil {
+		return err
+	}
+	message.Seq = seq
+	if err = tx.Create(message).Error; err != nil {
+		return err
+	}
+	return updateConversations(tx, message)
+}
+
+// nextSeq 递增并返回会话的消息序号，序号所在行在事务提交前一直被锁定，同一会话的消息串行分配序号
+func nextSeq(tx *gorm.DB, conversationId string) (int64, error) {
+	err := tx.Exec("INSERT INTO conversation_sequences (conversation_id, seq) VALUES (?, 1) ON DUPLICATE KEY UPDATE seq = seq + 1",
+		conversationId).Error
+	if err != nil {
+		return 0, err
+	}
+	var seq int64
+	err = tx.Raw("SELECT seq FROM conversation_sequences WHERE conversation_id = ?", conversationId).Scan(&seq).Error
+	return seq, err
+}
+
+// GetQuote 回复的消息的内容摘要及其发送者用户名，消息已撤回时摘要为空
+func (m *messageService) GetQuote(msgId int32) (string, string) {
+	db := pool.GetDB()
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return "", ""
+	}
+	var from model.User
+	db.Find(&from, "id = ?", message.FromUserId)
+	return snippet(message.Content, message.ContentType), from.Username
+}
+
+// GetSavedMessage 发送者已经保存过的消息，未保存过时返回nil
+func (m *messageService) GetSavedMessage(fromUuid string, clientMsgId string) *model.Message {
+	if clientMsgId == "" {
+		return nil
+	}
+	db := pool.GetDB()
+	var fromUser model.User
+	db.Find(&fromUser, "uuid = ?", fromUuid)
+	if NULL_ID == fromUser.Id {
+		return nil
+	}
+	return findByClientMsgId(db, fromUser.Id, clientMsgId)
+}
+
+// findByClientMsgId 按发送者和客户端消息id查询消息
+func findByClientMsgId(db *gorm.DB, fromUserId int32, clientMsgId string) *model.Message {
+	if clientMsgId == "" {
+		return nil
+	}
+	var saved model.Message
+	db.Where("from_user_id = ? AND client_msg_id = ?", fromUserId, clientMsgId).Limit(1).Find(&saved)
+	if NULL_ID == saved.ID {
+		return nil
+	}
+	return &saved
+}
+
+const (
+	defaultSyncLimit = 100
+	maxSyncLimit     = 500
+)
+
+// SyncMessages
+//
+//	@Description: 离线消息同步，按客户端上报的每个会话的最大序号，返回其后缺失的消息(按序号升序)
+//	@receiver m
+//	@param userUuid 当前登录用户，只能同步自己参与的会话，已退出或被移出的会话直接跳过
+//	@param req
+//	@return []response.SyncResponse
+//	@return error
+func (m *messageService) SyncMessages(userUuid string, req request.SyncRequest) ([]response.SyncResponse, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+
+	result := make([]response.SyncResponse, 0, len(req.Conversations))
+	for _, conversation := range req.Conversations {
+		// 不能因为一个已离开的会话导致其余会话都无法同步
+		if !isParticipant(db, conversation.ConversationId, &user) {
+			continue
+		}
+		var messages []response.MessageResponse
+		query := messageQuery(db).Select(messageColumns).
+			Where("m.conversation_id = ? AND m.seq > ?", conversation.ConversationId, conversation.Seq)
+		notDeleted(query, userUuid).Order("m.seq").Limit(limit + 1).Scan(&messages)
+		prepareMessages(messages)
+		attachReactions(db, userUuid, messages)
+
+		hasMore := len(messages) > limit
+		if hasMore {
+			messages = messages[:limit]
+		}
+		result = append(result, response.SyncResponse{
+			ConversationId: conversation.ConversationId,
+			Messages:       messages,
+			HasMore:        hasMore,
+		})
+	}
+	return result, nil
+}
+
+// notDeleted 排除用户自己删除的消息
+func notDeleted(query *gorm.DB, userUuid string) *gorm.DB {
+	return query.Where("NOT EXISTS (SELECT 1 FROM message_deletions AS d JOIN users AS du ON du.id = d.user_id WHERE d.message_id = m.id AND du.uuid = ?)", userUuid)
+}
+
+// messageQuery 聊天记录查询，关联发送者、转发消息的原发送者以及回复的消息和它的发送者
+func messageQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("messages AS m").
+		Joins("LEFT JOIN users AS u ON m.from_user_id = u.id").
+		Joins("LEFT JOIN users AS fw ON fw.id = m.forward_from").
+		Joins("LEFT JOIN messages AS rm ON rm.id = m.reply_to").
+		Joins("LEFT JOIN users AS ru ON ru.id = rm.from_user_id")
+}
+
+// prepareMessages 已撤回的消息只保留占位，不返回内容；回复的消息只返回引用内容的摘要
+func prepareMessages(messages []response.MessageResponse) {
+	for i := range messages {
+		prepareMessage(&messages[i])
+	}
+}
+
+func prepareMessage(message *response.MessageResponse) {
+	if message.Recalled {
+		message.Content = ""
+		message.Url = ""
+	}
+	if message.ReplyTo > 0 {
+		message.Quote = snippet(message.Quote, message.QuoteContentType)
+	}
+}
+
+// 引用摘要的最大长度
+const snippetLength = 50
+
+// snippet 消息内容的摘要：文本截取前若干个字，文件、图片等显示为类型
+func snippet(content string, contentType int16) string {
+	switch contentType {
+	case constant.TEXT:
+		runes := []rune(content)
+		if len(runes) > snippetLength {
+			return string(runes[:snippetLength]) + "..."
+		}
+		return content
+	case constant.FILE:
+		return "[文件]"
+	case constant.IMAGE:
+		return "[图片]"
+	case constant.AUDIO:
+		return "[语音]"
+	case constant.VIDEO:
+		return "[视频]"
+	default:
+		return content
+	}
+}
+
+// isParticipant
+//
+//	@Description: 用户是否可以查看会话的消息，用于查询类操作。单聊会话id中包含该用户的uuid，群聊会话需要是群成员；
+//	@Description: 已解散的群为解散时的成员(解散时成员记录与群使用同一个删除时间)，聊天记录只读，发送消息等操作另行校验
+//	@param db
+//	@param conversationId
+//	@param user
+//	@return bool
+func isParticipant(db *gorm.DB, conversationId string, user *model.User) bool {
+	if !util.IsGroupConversation(conversationId) {
+		for _, uuid := range util.ConversationMembers(conversationId) {
+			if uuid == user.Uuid {
+				return true
+			}
+		}
+		return false
+	}
+	var count int64
+	db.Table("group_members AS gm").Joins("JOIN `groups` AS g ON g.id = gm.group_id").
+		Where("g.uuid = ? AND gm.user_id = ? AND gm.deleted_at = g.deleted_at", conversationId, user.Id).Count(&count)
+	return count > 0
+}
+
+const defaultRecallWindow = 120 * time.Second
+
+// RecallMessage
+//
+//	@Description: 撤回消息，只有发送者可以在发出后的撤回时限内撤回，撤回后所有人不可见
+//	@Description: 撤回使用软删除，聊天记录中保留不含内容的占位
+//	@receiver m
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@return *MessageTarget 消息所在的会话，用于推送撤回事件
+//	@return error
+func (m *messageService) RecallMessage(userUuid string, msgId int64) (*MessageTarget, error) {
+	db := pool.GetDB()
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return nil, errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id {
+		return nil, errors.New("只能撤回自己发送的消息")
+	}
+	window := time.Duration(config.GetConfig().Message.RecallWindow) * time.Second
+	if window <= 0 {
+		window = defaultRecallWindow
+	}
+	if time.Since(message.CreatedAt) > window {
+		return nil, errors.New("消息发出已超过撤回时限")
+	}
+	// 已解散的群只读，已退出或被移出的成员也不能再撤回
+	if err := checkMessageSend(db, &message, user.Id); err != nil {
+		return nil, err
+	}
+
+	if err := db.Delete(&message).Error; err != nil {
+		return nil, err
+	}
+	SearchService.removeMessage(&message)
+	return newMessageTarget(db, &message, &user), nil
+}
+
+// DeleteMessage
+//
+//	@Description: 删除消息，只对自己隐藏，会话中的其他人仍然可见
+//	@receiver m
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@return error
+func (m *messageService) DeleteMessage(userUuid string, msgId int64) error {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return errors.New("用户不存在")
+	}
+	// 已撤回的消息也可以删除占位
+	var message model.Message
+	db.Unscoped().Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id && !isRecipient(db, &message, user.Id) {
+		return errors.New("不是该消息所在会话的成员")
+	}
+
+	deletion := model.MessageDeletion{UserId: user.Id, MessageId: message.ID}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deletion).Error
+}
+
+// 消息内容的最大长度，与messages.content字段一致
+const maxContentLength = 2500
+
+// EditMessage
+//
+//	@Description: 编辑消息，只有发送者可以编辑自己的文本消息，编辑前的内容保存到编辑历史
+//	@receiver m
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@param content 编辑后的内容
+//	@return *MessageTarget 消息所在的会话，用于推送编辑事件
+//	@return int64 编辑时间(毫秒)
+//	@return error
+func (m *messageService) EditMessage(userUuid string, msgId int64, content string) (*MessageTarget, int64, error) {
+	if content == "" {
+		return nil, 0, errors.New("消息内容不能为空")
+	}
+	if len([]rune(content)) > maxContentLength {
+		return nil, 0, errors.New("消息内容过长")
+	}
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, 0, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return nil, 0, errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id {
+		return nil, 0, errors.New("只能编辑自己发送的消息")
+	}
+	if message.ContentType != constant.TEXT {
+		return nil, 0, errors.New("只能编辑文本消息")
+	}
+	if message.Content == content {
+		return nil, 0, errors.New("消息内容没有变化")
+	}
+	if err := checkMessageSend(db, &message, user.Id); err != nil {
+		return nil, 0, err
+	}
+
+	editedAt := time.Now().UnixMilli()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		edit := model.MessageEdit{MessageId: message.ID, Content: message.Content}
+		if err := tx.Create(&edit).Error; err != nil {
+			return err
+		}
+		return tx.Model(&message).Updates(map[string]interface{}{"content": content, "edited_at": editedAt}).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	message.Content = content
+	SearchService.indexMessage(&message)
+	return newMessageTarget(db, &message, &user), editedAt, nil
+}
+
+// GetEditHistory
+//
+//	@Description: 消息的编辑历史，按编辑时间正序，会话中的成员都可以查看
+//	@receiver m
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@return []model.MessageEdit
+//	@return error
+func (m *messageService) GetEditHistory(userUuid string, msgId int64) ([]model.MessageEdit, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return nil, errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id && !isRecipient(db, &message, user.Id) {
+		return nil, errors.New("不是该消息所在会话的成员")
+	}
+
+	edits := make([]model.MessageEdit, 0)
+	db.Where("message_id = ?", message.ID).Order("id").Find(&edits)
+	return edits, nil
+}
+
+// MessageTarget 消息所在的会话，撤回、编辑等操作后据此将事件推送给会话中的用户
+type MessageTarget struct {
+	MessageType int32
+	To          string // 单聊为对方uuid，群聊为群uuid，与发送消息时的to一致
+	MsgId       int64
+	Seq         int64
+}
+
+// newMessageTarget 以操作者的视角得到消息所在的会话
+func newMessageTarget(db *gorm.DB, message *model.Message, operator *model.User) *MessageTarget {
+	target := &MessageTarget{MessageType: int32(message.MessageType), MsgId: int64(message.ID), Seq: message.Seq}
+	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
+		var group model.Group
+		db.Find(&group, "id = ?", message.ToUserId)
+		target.To = group.Uuid
+		return target
+	}
+	peerId := message.ToUserId
+	if peerId == operator.Id {
+		peerId = message.FromUserId
+	}
+	var peer model.User
+	db.Find(&peer, "id = ?", peerId)
+	target.To = peer.Uuid
+	return target
 }
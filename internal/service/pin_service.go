@@ -0,0 +1,144 @@
+package service
+
+import (
+	"chat-room/config"
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pinService struct {
+}
+
+var PinService = new(pinService)
+
+// 每个会话默认最多置顶的消息数
+const defaultMaxPins = 10
+
+// Pin
+//
+//	@Description: 置顶或取消置顶消息，单聊双方都可以操作，群聊需要有置顶权限(默认为群主和管理员)
+//	@Description: 已撤回的消息不能置顶，置顶数达到上限时需要先取消其他置顶
+//	@receiver p
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@param pin true为置顶，false为取消置顶
+//	@return *MessageTarget 消息所在的会话，用于推送置顶事件
+//	@return bool 置顶是否发生了变化
+//	@return error
+func (p *pinService) Pin(userUuid string, msgId int64, pin bool) (*MessageTarget, bool, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, false, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return nil, false, errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id && !isRecipient(db, &message, user.Id) {
+		return nil, false, errors.New("不是该消息所在会话的成员")
+	}
+	if !canPin(db, &message, &user) {
+		return nil, false, errors.New("没有权限置顶消息")
+	}
+	if err := checkMessageSend(db, &message, user.Id); err != nil {
+		return nil, false, err
+	}
+
+	target := newMessageTarget(db, &message, &user)
+	conversationId := message.ConversationId
+	if conversationId == "" {
+		conversationId = util.ConversationId(target.MessageType, user.Uuid, target.To)
+	}
+
+	if !pin {
+		result := db.Where("conversation_id = ? AND message_id = ?", conversationId, message.ID).Delete(&model.MessagePin{})
+		return target, result.RowsAffected > 0, result.Error
+	}
+
+	var changed bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 锁定会话的序号行，同一会话的置顶串行执行，避免并发置顶超过上限
+		tx.Exec("SELECT seq FROM conversation_sequences WHERE conversation_id = ? FOR UPDATE", conversationId)
+		if pinCount(tx, conversationId) >= maxPins() {
+			return errors.New("置顶消息已达上限")
+		}
+		messagePin := model.MessagePin{ConversationId: conversationId, MessageId: message.ID, UserId: user.Id}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&messagePin)
+		changed = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return target, changed, nil
+}
+
+// GetPins
+//
+//	@Description: 会话中置顶的消息，按置顶时间倒序，已撤回的消息不再显示
+//	@receiver p
+//	@param userUuid 当前登录用户
+//	@param conversationId
+//	@return []response.PinnedMessageResponse
+//	@return error
+func (p *pinService) GetPins(userUuid string, conversationId string) ([]response.PinnedMessageResponse, error) {
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	if !isParticipant(db, conversationId, &user) {
+		return nil, errors.New("不是该会话的成员")
+	}
+
+	pins := make([]response.PinnedMessageResponse, 0)
+	messageQuery(db).Select(messageColumns+", p.created_at AS pinned_at, pu.uuid AS pinned_by, pu.username AS pinned_by_username").
+		Joins("JOIN message_pins AS p ON p.message_id = m.id").
+		Joins("LEFT JOIN users AS pu ON pu.id = p.user_id").
+		Where("p.conversation_id = ? AND m.deleted_at = 0", conversationId).Order("p.id DESC").Scan(&pins)
+	for i := range pins {
+		prepareMessage(&pins[i].MessageResponse)
+	}
+	return pins, nil
+}
+
+// canPin 单聊双方都可以置顶，群聊需要有置顶权限
+func canPin(db *gorm.DB, message *model.Message, user *model.User) bool {
+	if message.MessageType != constant.MESSAGE_TYPE_GROUP {
+		return true
+	}
+	var group model.Group
+	db.Find(&group, "id = ?", message.ToUserId)
+	_, err := checkPermission(db, &group, user.Id, permission.GROUP_ACTION_PIN)
+	return err == nil
+}
+
+// pinCount 会话中置顶的未撤回消息数
+func pinCount(db *gorm.DB, conversationId string) int64 {
+	var count int64
+	db.Table("message_pins AS p").Joins("JOIN messages AS m ON m.id = p.message_id").
+		Where("p.conversation_id = ? AND m.deleted_at = 0", conversationId).Count(&count)
+	return count
+}
+
+// maxPins 每个会话最多置顶的消息数
+func maxPins() int64 {
+	if limit := config.GetConfig().Message.MaxPins; limit > 0 {
+		return int64(limit)
+	}
+	return defaultMaxPins
+}
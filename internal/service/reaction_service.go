@@ -0,0 +1,93 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type reactionService struct {
+}
+
+var ReactionService = new(reactionService)
+
+// 表情的最大长度，与message_reactions.emoji字段一致
+const maxEmojiLength = 32
+
+// React
+//
+//	@Description: 添加或取消表情回应，会话中的成员都可以对未撤回的消息回应
+//	@receiver r
+//	@param userUuid 当前登录用户
+//	@param msgId
+//	@param emoji
+//	@param add true为添加，false为取消
+//	@return *MessageTarget 消息所在的会话，用于推送回应事件
+//	@return bool 回应是否发生了变化，重复添加或取消不存在的回应时不需要推送
+//	@return error
+func (r *reactionService) React(userUuid string, msgId int64, emoji string, add bool) (*MessageTarget, bool, error) {
+	if emoji == "" || len(emoji) > maxEmojiLength {
+		return nil, false, errors.New("表情格式错误")
+	}
+	db := pool.GetDB()
+	migrateMessageTables(db)
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, false, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return nil, false, errors.New("消息不存在")
+	}
+	if message.FromUserId != user.Id && !isRecipient(db, &message, user.Id) {
+		return nil, false, errors.New("不是该消息所在会话的成员")
+	}
+	if err := checkMessageSend(db, &message, user.Id); err != nil {
+		return nil, false, err
+	}
+
+	var result *gorm.DB
+	if add {
+		reaction := model.MessageReaction{MessageId: message.ID, UserId: user.Id, Emoji: emoji}
+		result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	} else {
+		result = db.Where("message_id = ? AND user_id = ? AND emoji = ?", message.ID, user.Id, emoji).Delete(&model.MessageReaction{})
+	}
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return newMessageTarget(db, &message, &user), result.RowsAffected > 0, nil
+}
+
+// attachReactions 为一批消息附加按表情聚合的回应数，以及当前用户是否回应过
+func attachReactions(db *gorm.DB, userUuid string, messages []response.MessageResponse) {
+	if len(messages) == 0 {
+		return
+	}
+	ids := make([]int32, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	var reactions []struct {
+		MessageId int32
+		response.ReactionCount
+	}
+	db.Raw("SELECT r.message_id, r.emoji, COUNT(*) AS count, SUM(u.uuid = ?) > 0 AS reacted FROM message_reactions AS r "+
+		"LEFT JOIN users AS u ON u.id = r.user_id WHERE r.message_id IN ? GROUP BY r.message_id, r.emoji ORDER BY MIN(r.id)",
+		userUuid, ids).Scan(&reactions)
+
+	byMessage := make(map[int32][]response.ReactionCount)
+	for _, reaction := range reactions {
+		byMessage[reaction.MessageId] = append(byMessage[reaction.MessageId], reaction.ReactionCount)
+	}
+	for i := range messages {
+		messages[i].Reactions = byMessage[messages[i].ID]
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"time"
+
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type receiptService struct {
+}
+
+var ReceiptService = new(receiptService)
+
+// SaveReceipt
+//
+//	@Description: 记录接收人对消息的送达或已读回执，已读的消息同时视为已送达
+//	@receiver r
+//	@param userUuid 接收人
+//	@param msgId 服务端分配的消息id
+//	@param status constant.DELIVERED 或 constant.READ
+//	@return string 消息发送者的uuid，回执需要推送给发送者
+//	@return int64 回执时间(毫秒)
+//	@return bool 回执状态是否发生变化，重复的回执不需要再推送
+//	@return error
+func (r *receiptService) SaveReceipt(userUuid string, msgId int64, status string) (string, int64, bool, error) {
+	if status != constant.DELIVERED && status != constant.READ {
+		return "", 0, false, errors.New("不支持的回执类型")
+	}
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return "", 0, false, errors.New("用户不存在")
+	}
+	var message model.Message
+	db.Find(&message, "id = ?", msgId)
+	if NULL_ID == message.ID {
+		return "", 0, false, errors.New("消息不存在")
+	}
+	if !isRecipient(db, &message, user.Id) {
+		return "", 0, false, errors.New("不是该消息的接收人")
+	}
+	var sender model.User
+	db.Find(&sender, "id = ?", message.FromUserId)
+
+	var receipt model.MessageReceipt
+	db.Where("message_id = ? AND user_id = ?", message.ID, user.Id).Limit(1).Find(&receipt)
+	if (status == constant.DELIVERED && receipt.DeliveredAt > 0) || (status == constant.READ && receipt.ReadAt > 0) {
+		return sender.Uuid, 0, false, nil
+	}
+
+	now := time.Now().UnixMilli()
+	receipt = model.MessageReceipt{MessageId: message.ID, UserId: user.Id, DeliveredAt: now}
+	updates := map[string]interface{}{
+		"delivered_at": gorm.Expr("IF(delivered_at = 0, ?, delivered_at)", now),
+		"updated_at":   time.Now(),
+	}
+	if status == constant.READ {
+		receipt.ReadAt = now
+		updates["read_at"] = gorm.Expr("IF(read_at = 0, ?, read_at)", now)
+	}
+	err := db.Clauses(clause.OnConflict{DoUpdates: clause.Assignments(updates)}).Create(&receipt).Error
+	if err != nil {
+		return "", 0, false, err
+	}
+	// 已读回执同时推进会话的已读位置，用于计算未读数
+	if status == constant.READ && message.Seq > 0 {
+		if err = markRead(db, user.Id, message.ConversationId, message.Seq); err != nil {
+			return "", 0, false, err
+		}
+	}
+	return sender.Uuid, now, true, nil
+}
+
+// isRecipient 单聊消息的接收人，或者群聊消息所在群的其他成员
+func isRecipient(db *gorm.DB, message *model.Message, userId int32) bool {
+	if message.FromUserId == userId {
+		return false
+	}
+	if message.MessageType == constant.MESSAGE_TYPE_USER {
+		return message.ToUserId == userId
+	}
+	var count int64
+	db.Model(&model.GroupMember{}).Where("group_id = ? AND user_id = ?", message.ToUserId, userId).Count(&count)
+	return count > 0
+}
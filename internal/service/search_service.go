@@ -0,0 +1,153 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"
+	"chat-room/internal/model"
+	"chat-room/internal/search"
+	"chat-room/pkg/common/request"
+	"chat-room/pkg/common/response"
+	"chat-room/pkg/common/util"
+	"chat-room/pkg/errors"
+	"chat-room/pkg/global/log"
+	"strings"
+	"time"
+)
+
+type searchService struct {
+	engine search.Engine
+}
+
+var SearchService = new(searchService)
+
+// 搜索关键字的最大长度
+const maxKeywordLength = 100
+
+// SetEngine 设置检索引擎，启动时根据配置创建(见search.New)
+func (s *searchService) SetEngine(engine search.Engine) {
+	s.engine = engine
+}
+
+// Search
+//
+//	@Description: 在用户所在的全部会话中搜索文本内容，可以按会话、发送者、内容类型和时间范围过滤
+//	@Description: 结果按消息id从新到旧游标分页，已撤回的消息不会被搜到，自己删除的消息不返回
+//	@receiver s
+//	@param userUuid 当前登录用户
+//	@param req
+//	@return *response.SearchPage
+//	@return error
+func (s *searchService) Search(userUuid string, req request.SearchRequest) (*response.SearchPage, error) {
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword == "" {
+		return nil, errors.New("搜索关键字不能为空")
+	}
+	if len([]rune(keyword)) > maxKeywordLength {
+		return nil, errors.New("搜索关键字过长")
+	}
+	db := pool.GetDB()
+	migrateMessageTables(db)
+
+	var user model.User
+	db.Find(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	query := search.Query{Keyword: keyword, UserId: user.Id, ContentType: req.ContentType, Before: int32(req.Before)}
+	if req.ConversationId == "" {
+		query.Direct = true
+		// 包括已解散的群，解散时的成员仍然可以搜索其聊天记录
+		db.Table("group_members AS gm").Joins("JOIN `groups` AS g ON g.id = gm.group_id").
+			Where("gm.user_id = ? AND gm.deleted_at = g.deleted_at", user.Id).Pluck("gm.group_id", &query.GroupIds)
+	} else {
+		if !isParticipant(db, req.ConversationId, &user) {
+			return nil, errors.New("不是该会话的成员")
+		}
+		if util.IsGroupConversation(req.ConversationId) {
+			var group model.Group
+			db.Unscoped().Where("uuid = ?", req.ConversationId).Limit(1).Find(&group)
+			query.GroupIds = []int32{group.ID}
+		} else {
+			var peer model.User
+			db.Find(&peer, "uuid = ?", util.ConversationPeer(req.ConversationId, user.Uuid))
+			if NULL_ID == peer.Id {
+				return nil, errors.New("用户不存在")
+			}
+			query.Direct = true
+			query.PeerId = peer.Id
+		}
+	}
+	if req.From != "" {
+		var from model.User
+		db.Find(&from, "uuid = ?", req.From)
+		if NULL_ID == from.Id {
+			return nil, errors.New("发送者不存在")
+		}
+		query.FromUserId = from.Id
+	}
+	if req.Start > 0 {
+		query.Start = time.UnixMilli(req.Start)
+	}
+	if req.End > 0 {
+		query.End = time.UnixMilli(req.End)
+	}
+	query.Size = req.Size
+	if query.Size <= 0 {
+		query.Size = defaultPageSize
+	}
+	if query.Size > maxPageSize {
+		query.Size = maxPageSize
+	}
+
+	if s.engine == nil {
+		return nil, errors.New("检索引擎未初始化")
+	}
+	ids, hasMore, err := s.engine.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	page := &response.SearchPage{Messages: make([]response.MessageResponse, 0), HasMore: hasMore}
+	if len(ids) == 0 {
+		return page, nil
+	}
+	// 游标取检索结果的最后一条，自己删除的消息被过滤后不影响继续翻页
+	page.NextCursor = int64(ids[len(ids)-1])
+
+	notDeleted(messageQuery(db).Select(messageColumns).Where("m.id IN ?", ids), userUuid).
+		Order("m.id DESC").Scan(&page.Messages)
+	prepareMessages(page.Messages)
+	attachReactions(db, userUuid, page.Messages)
+	for i := range page.Messages {
+		page.Messages[i].Highlight = search.Highlight(page.Messages[i].Content, keyword)
+	}
+	return page, nil
+}
+
+// indexMessage 新消息或编辑后的消息加入检索索引，索引失败不影响消息本身
+func (s *searchService) indexMessage(message *model.Message) {
+	if s.engine == nil {
+		return
+	}
+	err := s.engine.Index(search.Document{
+		ID:          message.ID,
+		MessageType: message.MessageType,
+		FromUserId:  message.FromUserId,
+		ToUserId:    message.ToUserId,
+		ContentType: message.ContentType,
+		Content:     message.Content,
+		CreatedAt:   message.CreatedAt,
+	})
+	if err != nil {
+		log.Logger.Error("index message error", log.Any("index message error", err.Error()))
+	}
+}
+
+// removeMessage 撤回的消息从检索索引中删除
+func (s *searchService) removeMessage(message *model.Message) {
+	if s.engine == nil {
+		return
+	}
+	if err := s.engine.Remove(message.ID); err != nil {
+		log.Logger.Error("remove message index error", log.Any("remove message index error", err.Error()))
+	}
+}
@@ -106,7 +106,7 @@ func (u *userService) Login(user *model.User) bool {
 func (u *userService) ModifyUserInfo(user *model.User) error {
 	var queryUser *model.User
 	db := pool.GetDB()
-	db.First(&queryUser, "username = ?", user.Username)
+	db.First(&queryUser, "uuid = ?", user.Uuid)
 	log.Logger.Debug("queryUser", log.Any("queryUser", queryUser))
 	if queryUser.Id == 0 {
 		return errors.New("用户不存在")
@@ -245,3 +245,27 @@ func (u *userService) ModifyUserAvatar(avatar string, userUuid string) error {
 	db.Model(&queryUser).Update("avatar", avatar)
 	return nil
 }
+
+// GetPresenceAudience
+//
+//	@Description: 关注该用户在线状态的用户：双向好友以及所在群的其他成员(去重)
+//	@receiver u
+//	@param userUuid
+//	@return []string
+func (u *userService) GetPresenceAudience(userUuid string) []string {
+	var queryUser *model.User
+	db := pool.GetDB()
+	db.First(&queryUser, "uuid = ?", userUuid)
+	if NULL_ID == queryUser.Id {
+		return nil
+	}
+
+	var uuids []string
+	db.Raw("SELECT u.uuid FROM user_friends AS uf JOIN users AS u ON u.id = IF(uf.user_id = ?, uf.friend_id, uf.user_id) "+
+		"WHERE (uf.user_id = ? OR uf.friend_id = ?) AND uf.deleted_at = 0 AND u.id != ? "+
+		"UNION "+
+		"SELECT u.uuid FROM group_members AS gm JOIN group_members AS other ON other.group_id = gm.group_id JOIN users AS u ON u.id = other.user_id "+
+		"WHERE gm.user_id = ? AND gm.deleted_at = 0 AND other.deleted_at = 0 AND other.user_id != ?",
+		queryUser.Id, queryUser.Id, queryUser.Id, queryUser.Id, queryUser.Id, queryUser.Id).Scan(&uuids)
+	return uuids
+}
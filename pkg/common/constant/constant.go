@@ -4,10 +4,60 @@ const (
 	HEAT_BEAT = "heatbeat"
 	PONG      = "pong"
 
+	// 音视频通话信令，服务端只转发不保存
+	WEBRTC = "webrtc"
+
+	// 消息回执，服务端保存消息后回复给发送端，携带服务端分配的消息id
+	ACK = "ack"
+
+	// 消息回执，接收端上报送达或已读，服务端推送给消息发送者
+	DELIVERED = "delivered"
+	READ      = "read"
+
+	// 离线消息同步，客户端重连后上报每个会话已收到的最大序号，服务端补发缺失的消息
+	SYNC = "sync"
+
+	// 消息撤回(所有人不可见)与删除(仅自己不可见)，客户端发起操作，服务端推送给会话中的在线设备
+	RECALL = "recall"
+	DELETE = "delete"
+
+	// 编辑消息，content为编辑后的内容，只能编辑自己发送的文本消息
+	EDIT = "edit"
+
+	// 添加、取消表情回应，content为表情
+	REACT   = "react"
+	UNREACT = "unreact"
+	// 置顶、取消置顶消息
+	PIN   = "pin"
+	UNPIN = "unpin"
+	// 被移出群聊，服务端通知被移出的成员，content为系统消息内容
+	REMOVE = "remove"
+	// 群聊已解散，服务端逐个通知解散时的成员
+	DISSOLVE = "dissolve"
+
+	// 错误消息，客户端发来的消息处理失败时回复给发送端，content为错误原因
+	ERROR = "error"
+
+	// 在线状态消息，服务端推送好友的状态变化，客户端上报自己是否处于离开状态
+	PRESENCE = "presence"
+
+	// 在线状态
+	PRESENCE_ONLINE  = "online"
+	PRESENCE_AWAY    = "away"
+	PRESENCE_OFFLINE = "offline"
+
 	// 消息类型，单聊或者群聊
 	MESSAGE_TYPE_USER  = 1
 	MESSAGE_TYPE_GROUP = 2
 
+	// 群成员角色，数值越大权限越高
+	GROUP_ROLE_MEMBER = 0
+	GROUP_ROLE_ADMIN  = 1
+	GROUP_ROLE_OWNER  = 2
+
+	// 群聊中@所有人
+	MENTION_ALL = "all"
+
 	// 消息内容类型
 	TEXT         = 1 // 文本
 	FILE         = 2 // 文件
@@ -16,8 +66,25 @@ const (
 	VIDEO        = 5 // 视频
 	AUDIO_ONLINE = 6 // 语音通话
 	VIDEO_ONLINE = 7 // 视频通话
+	// 8-20已被客户端用于视频画面、屏幕共享以及通话信令等只转发不保存的消息
+	CHAT_RECORD = 21 // 合并转发的聊天记录
+	SYSTEM      = 22 // 系统消息，如成员退群、被移出群聊，由服务端生成
+
+	// token类型
+	ACCESS_TOKEN  = "access"
+	REFRESH_TOKEN = "refresh"
+
+	// 鉴权中间件写入gin上下文的当前登录用户uuid
+	CTX_USER_UUID = "userUuid"
 
 	// 消息队列类型
 	GO_CHANNEL = "gochannel"
 	KAFKA      = "kafka"
+	REDIS      = "redis"
+
+	// 在线状态注册表类型，多节点部署时与redis共用REDIS
+	LOCAL = "local"
+
+	// 消息检索引擎类型，单机部署也可以使用LOCAL进程内索引
+	MYSQL = "mysql"
 )
@@ -0,0 +1,23 @@
+package request
+
+// GroupRequest 修改群信息，未传的字段保持不变
+type GroupRequest struct {
+	Name   *string `json:"name"`
+	Notice *string `json:"notice"`
+}
+
+// MuteRequest 禁言群成员
+type MuteRequest struct {
+	Duration int64 `json:"duration"` // 禁言时长，单位秒，0为永久禁言
+}
+
+// AllMuteRequest 开启或关闭全员禁言
+type AllMuteRequest struct {
+	Muted bool `json:"muted"`
+}
+
+// InviteRequest 创建群邀请链接
+type InviteRequest struct {
+	ExpireIn int64 `json:"expireIn"` // 有效时长，单位秒，0为永不过期
+	MaxUses  int32 `json:"maxUses"`  // 最多使用次数，0为不限制
+}
@@ -4,4 +4,54 @@ type MessageRequest struct {
 	MessageType    int32  `json:"messageType"`
 	Uuid           string `json:"uuid"`
 	FriendUsername string `json:"friendUsername"`
+	Before         int64  `json:"before" form:"before"` // 游标：查询该消息id之前(更早)的消息
+	After          int64  `json:"after" form:"after"`   // 游标：查询该消息id之后(更新)的消息，与before同时指定时以after为准
+	Size           int    `json:"size" form:"size"`     // 每页条数，默认20，最多100
+}
+
+// SyncRequest 离线消息同步请求，携带客户端每个会话已经收到的最大序号
+type SyncRequest struct {
+	Conversations []ConversationSeq `json:"conversations"`
+	Limit         int               `json:"limit"` // 每个会话最多返回的条数
+}
+
+type ConversationSeq struct {
+	ConversationId string `json:"conversationId"`
+	Seq            int64  `json:"seq"`
+}
+
+// ConversationRequest 修改会话设置，字段为空时不修改
+type ConversationRequest struct {
+	Muted  *bool `json:"muted"`
+	Pinned *bool `json:"pinned"`
+}
+
+// EditMessageRequest 编辑消息
+type EditMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// ReactionRequest 添加表情回应
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// SearchRequest 消息搜索，除关键字外的条件均为可选
+type SearchRequest struct {
+	Keyword        string `json:"keyword" form:"keyword"`
+	ConversationId string `json:"conversationId" form:"conversationId"` // 只搜索该会话
+	From           string `json:"from" form:"from"`                     // 只搜索该用户(uuid)发送的消息
+	ContentType    int16  `json:"contentType" form:"contentType"`
+	Start          int64  `json:"start" form:"start"`   // 开始时间(毫秒)，包含
+	End            int64  `json:"end" form:"end"`       // 结束时间(毫秒)，不包含
+	Before         int64  `json:"before" form:"before"` // 游标：上一页返回的nextCursor
+	Size           int    `json:"size" form:"size"`     // 每页条数，默认20，最多100
+}
+
+// ForwardRequest 转发消息到指定的用户或群
+type ForwardRequest struct {
+	MessageIds  []int64 `json:"messageIds"`
+	To          string  `json:"to"`          // 单聊为用户uuid，群聊为群uuid
+	MessageType int32   `json:"messageType"` // 1.单聊 2.群聊
+	Merge       bool    `json:"merge"`       // 合并为一条聊天记录转发，否则逐条转发
 }
@@ -0,0 +1,6 @@
+package request
+
+// RefreshTokenRequest 刷新token请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
@@ -8,4 +8,47 @@ type GroupResponse struct {
 	CreatedAt time.Time `json:"createAt"`
 	Name      string    `json:"name"`
 	Notice    string    `json:"notice"`
+	AllMuted  bool      `json:"allMuted"` // 全员禁言
+	Role      int16     `json:"role"`     // 当前用户在群中的角色：0成员 1管理员 2群主
+}
+
+// GroupMemberResponse 群成员
+type GroupMemberResponse struct {
+	Uuid      string `json:"uuid"`
+	Username  string `json:"username"`
+	Avatar    string `json:"avatar"`
+	Nickname  string `json:"nickname"`
+	Mute      int16  `json:"mute"`      // 是否禁言中
+	MuteUntil int64  `json:"muteUntil"` // 禁言截止时间(毫秒)，0为永久禁言
+	Role      int16  `json:"role"`      // 0成员 1管理员 2群主
+}
+
+// GroupBanResponse 群黑名单中的用户
+type GroupBanResponse struct {
+	Uuid      string    `json:"uuid"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	Operator  string    `json:"operator"` // 操作者用户名
+	CreatedAt time.Time `json:"createAt"`
+}
+
+// GroupInviteResponse 群邀请链接
+type GroupInviteResponse struct {
+	Token     string    `json:"token"`
+	Creator   string    `json:"creator"`  // 创建者用户名
+	ExpireAt  int64     `json:"expireAt"` // 过期时间(毫秒)，0为永不过期
+	MaxUses   int32     `json:"maxUses"`  // 最多使用次数，0为不限制
+	Uses      int32     `json:"uses"`
+	Revoked   bool      `json:"revoked"`
+	CreatedAt time.Time `json:"createAt"`
+}
+
+// GroupInviteUseResponse 通过邀请链接入群的记录
+type GroupInviteUseResponse struct {
+	Token     string    `json:"token"`
+	Creator   string    `json:"creator"` // 邀请链接的创建者用户名
+	Uuid      string    `json:"uuid"`    // 入群的用户
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"createAt"` // 入群时间
 }
@@ -3,14 +3,99 @@ package response
 import "time"
 
 type MessageResponse struct {
-	ID           int32     `json:"id" gorm:"primarykey"`
-	FromUserId   int32     `json:"fromUserId" gorm:"index"`
-	ToUserId     int32     `json:"toUserId" gorm:"index"`
-	Content      string    `json:"content" gorm:"type:varchar(2500)"`
-	ContentType  int16     `json:"contentType" gorm:"comment:'消息内容类型：1文字，2语音，3视频'"`
-	CreatedAt    time.Time `json:"createAt"`
-	FromUsername string    `json:"fromUsername"`
-	ToUsername   string    `json:"toUsername"`
-	Avatar       string    `json:"avatar"`
-	Url          string    `json:"url"`
+	ID                  int32           `json:"id" gorm:"primarykey"`
+	FromUserId          int32           `json:"fromUserId" gorm:"index"`
+	ToUserId            int32           `json:"toUserId" gorm:"index"`
+	Content             string          `json:"content" gorm:"type:varchar(2500)"`
+	ContentType         int16           `json:"contentType" gorm:"comment:'消息内容类型：1文字，2语音，3视频'"`
+	CreatedAt           time.Time       `json:"createAt"`
+	FromUsername        string          `json:"fromUsername"`
+	ToUsername          string          `json:"toUsername"`
+	Avatar              string          `json:"avatar"`
+	Url                 string          `json:"url"`
+	ClientMsgId         string          `json:"clientMsgId"`
+	MessageType         int16           `json:"messageType"`
+	FromUuid            string          `json:"fromUuid"`
+	ConversationId      string          `json:"conversationId"`
+	Seq                 int64           `json:"seq"`
+	Recalled            bool            `json:"recalled"`   // 已撤回，content和url为空
+	Edited              bool            `json:"edited"`     // 编辑过，编辑历史通过 GET /message/:id/edits 查询
+	EditedAt            int64           `json:"editedAt"`   // 最后编辑时间(毫秒)
+	ReplyTo             int32           `json:"replyTo"`    // 回复的消息id
+	ThreadRoot          int32           `json:"threadRoot"` // 所在话题的根消息id
+	ReplyCount          int64           `json:"replyCount"` // 作为话题根消息时的回复数
+	Quote               string          `json:"quote"`      // 回复的消息的内容摘要，回复的消息已撤回时为空
+	QuoteContentType    int16           `json:"quoteContentType"`
+	QuoteFrom           string          `json:"quoteFrom"`                    // 回复的消息的发送者用户名
+	Mentions            string          `json:"mentions"`                     // @的成员uuid，逗号分隔，all为@所有人
+	ForwardFrom         string          `json:"forwardFrom"`                  // 转发的消息的原发送者uuid，非转发消息为空
+	ForwardFromUsername string          `json:"forwardFromUsername"`          // 转发的消息的原发送者用户名
+	Reactions           []ReactionCount `json:"reactions" gorm:"-"`           // 按表情聚合的回应
+	Highlight           string          `json:"highlight,omitempty" gorm:"-"` // 搜索结果中命中关键字的摘要，关键字用<em>标记
+	DeliveredCount      int64           `json:"deliveredCount"`               // 已送达的接收人数，单聊为0或1
+	ReadCount           int64           `json:"readCount"`                    // 已读的接收人数
+}
+
+// MessagePage 聊天记录分页，一页内的消息按时间正序排列
+type MessagePage struct {
+	Messages   []MessageResponse `json:"messages"`
+	NextCursor int64             `json:"nextCursor"` // 继续翻页时作为before(向前翻页)或after(向后翻页)传入
+	HasMore    bool              `json:"hasMore"`
+}
+
+// SearchPage 消息搜索结果，按时间从新到旧排列
+type SearchPage struct {
+	Messages   []MessageResponse `json:"messages"`
+	NextCursor int64             `json:"nextCursor"` // 继续翻页时作为before传入
+	HasMore    bool              `json:"hasMore"`
+}
+
+// ThreadResponse 话题，根消息以及分页的回复
+type ThreadResponse struct {
+	Root    MessageResponse `json:"root"`
+	Replies *MessagePage    `json:"replies"`
+}
+
+// SyncResponse 单个会话需要补齐的消息
+type SyncResponse struct {
+	ConversationId string            `json:"conversationId"`
+	Messages       []MessageResponse `json:"messages"`
+	HasMore        bool              `json:"hasMore"` // 超过单次同步条数，需要从最后一条的序号继续同步
+}
+
+// ConversationResponse 会话列表项
+type ConversationResponse struct {
+	ConversationId   string     `json:"conversationId"`
+	MessageType      int16      `json:"messageType"`
+	Uuid             string     `json:"uuid"` // 单聊为对方uuid，群聊为群uuid
+	Name             string     `json:"name"`
+	Avatar           string     `json:"avatar"`
+	Muted            bool       `json:"muted"`
+	Pinned           bool       `json:"pinned"`
+	ReadSeq          int64      `json:"readSeq"`
+	Seq              int64      `json:"seq"`
+	Unread           int64      `json:"unread"`
+	MentionUnread    int64      `json:"mentionUnread"` // 未读消息中@自己的条数，免打扰的会话也需要提醒
+	LastMessageId    int32      `json:"lastMessageId"`
+	LastContent      string     `json:"lastContent"`
+	LastContentType  int16      `json:"lastContentType"`
+	LastFromUsername string     `json:"lastFromUsername"`
+	LastMessageAt    *time.Time `json:"lastMessageAt"`
+	LastRecalled     bool       `json:"lastRecalled"` // 最后一条消息已撤回
+	Dissolved        bool       `json:"dissolved"`    // 群聊已解散，只能查看聊天记录
+}
+
+// ReactionCount 消息上某个表情的回应数
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int64  `json:"count"`
+	Reacted bool   `json:"reacted"` // 当前用户是否回应了该表情
+}
+
+// PinnedMessageResponse 会话中置顶的消息
+type PinnedMessageResponse struct {
+	MessageResponse
+	PinnedBy         string    `json:"pinnedBy"` // 置顶操作者uuid
+	PinnedByUsername string    `json:"pinnedByUsername"`
+	PinnedAt         time.Time `json:"pinnedAt"`
 }
@@ -0,0 +1,24 @@
+package response
+
+import "chat-room/internal/model"
+
+// LoginResponse
+// @Description: 登录回包，用户信息平铺在顶层以兼容原有客户端，同时附带签发的token
+type LoginResponse struct {
+	model.User
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenResponse 刷新token回包
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// PresenceResponse 用户在线状态(online/away/offline)以及最后在线时间(毫秒)
+type PresenceResponse struct {
+	Uuid     string `json:"uuid"`
+	Status   string `json:"status"`
+	LastSeen int64  `json:"lastSeen"`
+}
@@ -0,0 +1,116 @@
+package token
+
+import (
+	"time"
+
+	"chat-room/config"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/errors"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 签发配置，由Init在启动时设置
+var jwtConf config.JwtConfig
+
+// 早期版本配置文件中提交过的默认密钥，已经公开，不能再使用
+const defaultSecret = "chat-room-secret"
+
+// Init
+//
+//	@Description: 设置token签发配置，启动时调用。未配置签名密钥或仍为默认密钥时返回错误，服务不应启动
+//	@param conf
+//	@return error
+func Init(conf config.JwtConfig) error {
+	if conf.Secret == "" || conf.Secret == defaultSecret {
+		return errors.New("未配置jwt签名密钥，请在配置文件[jwt]中设置secret或设置环境变量JWT_SECRET")
+	}
+	jwtConf = conf
+	return nil
+}
+
+// Claims
+// @Description: token中携带的用户信息
+type Claims struct {
+	Uuid      string `json:"uuid"`      // 用户uuid
+	TokenType string `json:"tokenType"` // token类型 access/refresh
+	jwt.RegisteredClaims
+}
+
+// GenerateToken
+//
+//	@Description: 为用户签发指定类型的token
+//	@param uuid
+//	@param tokenType
+//	@return string
+//	@return error
+func GenerateToken(uuid, tokenType string) (string, error) {
+	conf := jwtConf
+	if conf.Secret == "" {
+		return "", errors.New("token签发配置未初始化")
+	}
+	expire := conf.AccessExpire
+	if tokenType == constant.REFRESH_TOKEN {
+		expire = conf.RefreshExpire
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Uuid:      uuid,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    conf.Issuer,
+			Subject:   uuid,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(expire) * time.Second)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(conf.Secret))
+}
+
+// GenerateTokenPair
+//
+//	@Description: 登录或刷新时同时签发accessToken和refreshToken
+//	@param uuid
+//	@return accessToken
+//	@return refreshToken
+//	@return err
+func GenerateTokenPair(uuid string) (accessToken, refreshToken string, err error) {
+	accessToken, err = GenerateToken(uuid, constant.ACCESS_TOKEN)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = GenerateToken(uuid, constant.REFRESH_TOKEN)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// ParseToken
+//
+//	@Description: 校验token签名、有效期以及类型，返回其中的用户信息
+//	@param tokenStr
+//	@param tokenType
+//	@return *Claims
+//	@return error
+func ParseToken(tokenStr, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	t, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		// 只接受HMAC签名，防止alg被篡改为none等算法
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("token签名算法不合法")
+		}
+		if jwtConf.Secret == "" {
+			return nil, errors.New("token签发配置未初始化")
+		}
+		return []byte(jwtConf.Secret), nil
+	})
+	if err != nil || !t.Valid {
+		return nil, errors.New("token无效或已过期")
+	}
+	if claims.TokenType != tokenType || claims.Uuid == "" {
+		return nil, errors.New("token类型不正确")
+	}
+	return claims, nil
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"strings"
+
+	"chat-room/pkg/common/constant"
+)
+
+const conversationSeparator = ":"
+
+// ConversationId 消息所属的会话：单聊为双方uuid按字典序拼接，群聊为群uuid
+func ConversationId(messageType int32, from, to string) string {
+	if messageType == constant.MESSAGE_TYPE_GROUP {
+		return to
+	}
+	if from < to {
+		return from + conversationSeparator + to
+	}
+	return to + conversationSeparator + from
+}
+
+// IsGroupConversation 群聊的会话id为群uuid，不包含单聊的分隔符
+func IsGroupConversation(conversationId string) bool {
+	return !strings.Contains(conversationId, conversationSeparator)
+}
+
+// ConversationMembers 单聊会话的双方uuid
+func ConversationMembers(conversationId string) []string {
+	return strings.Split(conversationId, conversationSeparator)
+}
+
+// ConversationPeer 单聊会话中对方的uuid，自己发给自己的会话返回自己
+func ConversationPeer(conversationId, self string) string {
+	for _, uuid := range ConversationMembers(conversationId) {
+		if uuid != self {
+			return uuid
+		}
+	}
+	return self
+}
@@ -32,6 +32,19 @@ type Message struct {
 	Url                  string   `protobuf:"bytes,9,opt,name=url,proto3" json:"url,omitempty"`
 	FileSuffix           string   `protobuf:"bytes,10,opt,name=fileSuffix,proto3" json:"fileSuffix,omitempty"`
 	File                 []byte   `protobuf:"bytes,11,opt,name=file,proto3" json:"file,omitempty"`
+	Device               string   `protobuf:"bytes,12,opt,name=device,proto3" json:"device,omitempty"`
+	Timestamp            int64    `protobuf:"varint,13,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ClientMsgId          string   `protobuf:"bytes,14,opt,name=clientMsgId,proto3" json:"clientMsgId,omitempty"`
+	MsgId                int64    `protobuf:"varint,15,opt,name=msgId,proto3" json:"msgId,omitempty"`
+	Seq                  int64    `protobuf:"varint,16,opt,name=seq,proto3" json:"seq,omitempty"`
+	EditedAt             int64    `protobuf:"varint,17,opt,name=editedAt,proto3" json:"editedAt,omitempty"`
+	ReplyTo              int64    `protobuf:"varint,18,opt,name=replyTo,proto3" json:"replyTo,omitempty"`
+	ThreadRoot           int64    `protobuf:"varint,19,opt,name=threadRoot,proto3" json:"threadRoot,omitempty"`
+	Quote                string   `protobuf:"bytes,20,opt,name=quote,proto3" json:"quote,omitempty"`
+	QuoteFrom            string   `protobuf:"bytes,21,opt,name=quoteFrom,proto3" json:"quoteFrom,omitempty"`
+	Mentions             []string `protobuf:"bytes,22,rep,name=mentions,proto3" json:"mentions,omitempty"`
+	ForwardFrom          string   `protobuf:"bytes,23,opt,name=forwardFrom,proto3" json:"forwardFrom,omitempty"`
+	ForwardFromUsername  string   `protobuf:"bytes,24,opt,name=forwardFromUsername,proto3" json:"forwardFromUsername,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -138,6 +151,97 @@ func (m *Message) GetFile() []byte {
 	return nil
 }
 
+func (m *Message) GetDevice() string {
+	if m != nil {
+		return m.Device
+	}
+	return ""
+}
+
+func (m *Message) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Message) GetClientMsgId() string {
+	if m != nil {
+		return m.ClientMsgId
+	}
+	return ""
+}
+
+func (m *Message) GetMsgId() int64 {
+	if m != nil {
+		return m.MsgId
+	}
+	return 0
+}
+
+func (m *Message) GetSeq() int64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+func (m *Message) GetEditedAt() int64 {
+	if m != nil {
+		return m.EditedAt
+	}
+	return 0
+}
+
+func (m *Message) GetReplyTo() int64 {
+	if m != nil {
+		return m.ReplyTo
+	}
+	return 0
+}
+
+func (m *Message) GetThreadRoot() int64 {
+	if m != nil {
+		return m.ThreadRoot
+	}
+	return 0
+}
+
+func (m *Message) GetQuote() string {
+	if m != nil {
+		return m.Quote
+	}
+	return ""
+}
+
+func (m *Message) GetQuoteFrom() string {
+	if m != nil {
+		return m.QuoteFrom
+	}
+	return ""
+}
+
+func (m *Message) GetMentions() []string {
+	if m != nil {
+		return m.Mentions
+	}
+	return nil
+}
+
+func (m *Message) GetForwardFrom() string {
+	if m != nil {
+		return m.ForwardFrom
+	}
+	return ""
+}
+
+func (m *Message) GetForwardFromUsername() string {
+	if m != nil {
+		return m.ForwardFromUsername
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Message)(nil), "protocol.Message")
 }
@@ -145,19 +249,29 @@ func init() {
 func init() { proto.RegisterFile("protocol/message.proto", fileDescriptor_89254f84d2f8e90f) }
 
 var fileDescriptor_89254f84d2f8e90f = []byte{
-	// 219 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x90, 0xc1, 0x4a, 0xc4, 0x30,
-	0x10, 0x86, 0x49, 0x76, 0xb7, 0xdd, 0x9d, 0x5d, 0x44, 0xe6, 0xb0, 0xcc, 0x49, 0xc2, 0x9e, 0x7a,
-	0xd2, 0x83, 0xcf, 0xe1, 0xa5, 0xea, 0x03, 0xc4, 0x32, 0x91, 0x42, 0xda, 0x94, 0x34, 0x15, 0x7d,
-	0x1c, 0xdf, 0x54, 0x32, 0x6d, 0xa1, 0xde, 0xfe, 0xff, 0xfb, 0xf8, 0x03, 0x13, 0xb8, 0x0e, 0x31,
-	0xa4, 0xd0, 0x04, 0xff, 0xd4, 0xf1, 0x38, 0xda, 0x4f, 0x7e, 0x14, 0x80, 0xc7, 0x95, 0xdf, 0x7e,
-	0x35, 0x94, 0x2f, 0xb3, 0xc3, 0x2b, 0x14, 0xf6, 0xcb, 0x26, 0x1b, 0x49, 0x19, 0x55, 0x9d, 0xea,
-	0xa5, 0xe1, 0x0d, 0x2e, 0x2e, 0x86, 0xee, 0x7d, 0xe4, 0xd8, 0xdb, 0x8e, 0x49, 0x8b, 0xfd, 0xc7,
-	0x10, 0x61, 0x9f, 0x3b, 0xed, 0xc4, 0x49, 0xc6, 0x3b, 0xd0, 0x29, 0xd0, 0x5e, 0x88, 0x4e, 0x01,
-	0x09, 0xca, 0x26, 0xf4, 0x89, 0xfb, 0x44, 0x07, 0x81, 0x6b, 0x45, 0x03, 0xe7, 0x25, 0xbe, 0xfd,
-	0x0c, 0x4c, 0x85, 0x51, 0xd5, 0xa1, 0xde, 0xa2, 0xfc, 0x7e, 0xca, 0xaa, 0x9c, 0xdf, 0xcf, 0x39,
-	0xaf, 0x96, 0xb3, 0x64, 0x75, 0x9c, 0x57, 0x1b, 0x84, 0xf7, 0xb0, 0x9b, 0xa2, 0xa7, 0x93, 0x8c,
-	0x72, 0xc4, 0x07, 0x00, 0xd7, 0x7a, 0x7e, 0x9d, 0x9c, 0x6b, 0xbf, 0x09, 0x44, 0x6c, 0x88, 0xdc,
-	0xd1, 0x7a, 0xa6, 0xb3, 0x51, 0xd5, 0xa5, 0x96, 0xfc, 0x51, 0xc8, 0x6f, 0x3d, 0xff, 0x05, 0x00,
-	0x00, 0xff, 0xff, 0xb2, 0x77, 0xfe, 0xe2, 0x4e, 0x01, 0x00, 0x00,
+	// 381 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0x4f, 0x6f, 0xd3, 0x40,
+	0x10, 0xc5, 0xe5, 0xb8, 0x4e, 0xe2, 0x69, 0x28, 0x65, 0x5b, 0xc2, 0x08, 0x21, 0x64, 0xf5, 0xe4,
+	0x13, 0x20, 0xf1, 0x09, 0xb8, 0x20, 0x71, 0xe8, 0xc5, 0x94, 0x0f, 0xb0, 0xc4, 0xe3, 0xb2, 0x92,
+	0xed, 0x75, 0xd7, 0x93, 0x40, 0x3e, 0x0b, 0x5f, 0x16, 0xcd, 0x6c, 0xfe, 0x18, 0xa9, 0xb7, 0xf7,
+	0x7e, 0x2f, 0xcf, 0xd9, 0xb7, 0x36, 0xac, 0x87, 0xe0, 0xd9, 0x6f, 0x7c, 0xfb, 0xb1, 0xa3, 0x71,
+	0xb4, 0x8f, 0xf4, 0x41, 0x81, 0x59, 0x1e, 0xf9, 0xdd, 0xdf, 0x0c, 0x16, 0xf7, 0x31, 0x33, 0x6b,
+	0x98, 0xdb, 0x9d, 0x65, 0x1b, 0x30, 0x29, 0x92, 0x32, 0xaf, 0x0e, 0xce, 0xdc, 0xc1, 0xaa, 0x09,
+	0xbe, 0xfb, 0x31, 0x52, 0xe8, 0x6d, 0x47, 0x38, 0xd3, 0xf4, 0x3f, 0x66, 0x0c, 0x5c, 0x88, 0xc7,
+	0x54, 0x33, 0xd5, 0xe6, 0x0a, 0x66, 0xec, 0xf1, 0x42, 0xc9, 0x8c, 0xbd, 0x41, 0x58, 0x6c, 0x7c,
+	0xcf, 0xd4, 0x33, 0x66, 0x0a, 0x8f, 0xd6, 0x14, 0x70, 0x79, 0x90, 0x0f, 0xfb, 0x81, 0x70, 0x5e,
+	0x24, 0x65, 0x56, 0x4d, 0x91, 0x3c, 0x9f, 0x25, 0x5a, 0xc4, 0xe7, 0x8b, 0x96, 0xd6, 0x61, 0x96,
+	0xb6, 0x96, 0xb1, 0x35, 0x41, 0xe6, 0x1a, 0xd2, 0x6d, 0x68, 0x31, 0xd7, 0x92, 0x48, 0xf3, 0x1e,
+	0xa0, 0x71, 0x2d, 0x7d, 0xdf, 0x36, 0x8d, 0xfb, 0x83, 0xa0, 0xc1, 0x84, 0xe8, 0x0e, 0xd7, 0x12,
+	0x5e, 0x16, 0x49, 0xb9, 0xaa, 0x54, 0xcb, 0xbd, 0xd4, 0xb4, 0x73, 0x1b, 0xc2, 0x55, 0xbc, 0x97,
+	0xe8, 0xcc, 0x3b, 0xc8, 0xd9, 0x75, 0x34, 0xb2, 0xed, 0x06, 0x7c, 0x51, 0x24, 0x65, 0x5a, 0x9d,
+	0x81, 0x6e, 0x6a, 0x1d, 0xf5, 0x7c, 0x3f, 0x3e, 0x7e, 0xab, 0xf1, 0x4a, 0xab, 0x53, 0x64, 0x6e,
+	0x21, 0xeb, 0x34, 0x7b, 0xa9, 0xdd, 0x68, 0xe4, 0xcc, 0x23, 0x3d, 0xe1, 0xb5, 0x32, 0x91, 0xe6,
+	0x2d, 0x2c, 0xa9, 0x76, 0x4c, 0xf5, 0x17, 0xc6, 0x57, 0x8a, 0x4f, 0x5e, 0xee, 0x34, 0xd0, 0xd0,
+	0xee, 0x1f, 0x3c, 0x1a, 0x8d, 0x8e, 0x56, 0x96, 0xf2, 0xaf, 0x40, 0xb6, 0xae, 0xbc, 0x67, 0xbc,
+	0xd1, 0x70, 0x42, 0xe4, 0xdf, 0x9f, 0xb6, 0x9e, 0x09, 0x6f, 0xf5, 0x64, 0xd1, 0xc8, 0x26, 0x15,
+	0x5f, 0xe5, 0x65, 0xbe, 0xd6, 0xe4, 0x0c, 0xe4, 0x24, 0x1d, 0xf5, 0xec, 0x7c, 0x3f, 0xe2, 0xba,
+	0x48, 0xcb, 0xbc, 0x3a, 0x79, 0xd9, 0xdb, 0xf8, 0xf0, 0xdb, 0x86, 0x5a, 0xbb, 0x6f, 0xe2, 0xde,
+	0x09, 0x32, 0x9f, 0xe0, 0x66, 0x62, 0x4f, 0x9f, 0x13, 0xea, 0x2f, 0x9f, 0x8b, 0x7e, 0xce, 0xf5,
+	0x3b, 0xfd, 0xfc, 0x6f, 0x00, 0xc0, 0x5d, 0x02, 0x38, 0xc8, 0x02, 0x00, 0x00,
 }
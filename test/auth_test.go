@@ -0,0 +1,62 @@
+package test
+
+import (
+	"testing"
+
+	"chat-room/config"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/token"
+)
+
+func initToken(t *testing.T) {
+	if err := token.Init(config.JwtConfig{Secret: "test-secret", Issuer: "chat_room", AccessExpire: 60, RefreshExpire: 600}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenInit(t *testing.T) {
+	// 空密钥和公开的默认密钥都不能用于签发token
+	for _, secret := range []string{"", "chat-room-secret"} {
+		if err := token.Init(config.JwtConfig{Secret: secret}); err == nil {
+			t.Fatalf("secret %q accepted", secret)
+		}
+	}
+}
+
+func TestTokenPair(t *testing.T) {
+	initToken(t)
+	accessToken, refreshToken, err := token.GenerateTokenPair("user-uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := token.ParseToken(accessToken, constant.ACCESS_TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Uuid != "user-uuid" {
+		t.Fatalf("uuid = %s, want user-uuid", claims.Uuid)
+	}
+
+	// refreshToken不能当作accessToken使用，反之亦然
+	if _, err = token.ParseToken(refreshToken, constant.ACCESS_TOKEN); err == nil {
+		t.Fatal("refresh token accepted as access token")
+	}
+	if _, err = token.ParseToken(accessToken, constant.REFRESH_TOKEN); err == nil {
+		t.Fatal("access token accepted as refresh token")
+	}
+}
+
+func TestTokenTampered(t *testing.T) {
+	initToken(t)
+	accessToken, err := token.GenerateToken("user-uuid", constant.ACCESS_TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = token.ParseToken(accessToken+"x", constant.ACCESS_TOKEN); err == nil {
+		t.Fatal("tampered token accepted")
+	}
+	if _, err = token.ParseToken("", constant.ACCESS_TOKEN); err == nil {
+		t.Fatal("empty token accepted")
+	}
+}
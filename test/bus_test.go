@@ -0,0 +1,103 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/config"
+	"chat-room/internal/bus"
+	"chat-room/pkg/common/constant"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// receive 等待订阅回调收到消息，超时返回false
+func receive(ch chan []byte) ([]byte, bool) {
+	select {
+	case data := <-ch:
+		return data, true
+	case <-time.After(time.Second):
+		return nil, false
+	}
+}
+
+func TestChannelBus(t *testing.T) {
+	b, err := bus.New(config.TomlConfig{MsgChannelType: config.MsgChannelType{ChannelType: constant.GO_CHANNEL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个订阅者都能收到总线上的每条消息
+	first, second := make(chan []byte, 1), make(chan []byte, 1)
+	_ = b.Subscribe(func(data []byte) { first <- data })
+	_ = b.Subscribe(func(data []byte) { second <- data })
+
+	if err = b.Publish("key", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []chan []byte{first, second} {
+		data, ok := receive(ch)
+		if !ok || string(data) != "hello" {
+			t.Fatalf("got %q, want hello", data)
+		}
+	}
+
+	_ = b.Close()
+	if err = b.Publish("key", []byte("closed")); err == nil {
+		t.Fatal("publish after close should fail")
+	}
+}
+
+func TestUnknownBus(t *testing.T) {
+	if _, err := bus.New(config.TomlConfig{MsgChannelType: config.MsgChannelType{ChannelType: "unknown"}}); err == nil {
+		t.Fatal("unknown channel type should fail")
+	}
+}
+
+// TestRedisBus 使用进程内的miniredis模拟两个节点通过redis交换消息
+func TestRedisBus(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	nodeA := bus.NewRedisBus(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "go-chat-message", "node-a")
+	nodeB := bus.NewRedisBus(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "go-chat-message", "node-b")
+	defer nodeA.Close()
+	defer nodeB.Close()
+
+	receivedA, receivedB := make(chan []byte, 1), make(chan []byte, 1)
+	if err = nodeA.Subscribe(func(data []byte) { receivedA <- data }); err != nil {
+		t.Fatal(err)
+	}
+	if err = nodeB.Subscribe(func(data []byte) { receivedB <- data }); err != nil {
+		t.Fatal(err)
+	}
+
+	// 二进制的protobuf消息需要原样送达
+	payload := []byte{0x0a, 0x00, 0xff, 'h', 'i'}
+	if err = nodeA.Publish("a:b", payload); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []chan []byte{receivedA, receivedB} {
+		data, ok := receive(ch)
+		if !ok || string(data) != string(payload) {
+			t.Fatalf("got %v, want %v", data, payload)
+		}
+	}
+
+	// 定向投递只有目标节点能收到
+	if err = nodeA.PublishTo("node-b", "a:b", []byte("only b")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := receive(receivedB); !ok || string(data) != "only b" {
+		t.Fatalf("got %q, want only b", data)
+	}
+	select {
+	case data := <-receivedA:
+		t.Fatalf("node-a should not receive %q", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
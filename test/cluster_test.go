@@ -0,0 +1,165 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/config"
+	"chat-room/internal/presence"
+	"chat-room/pkg/common/constant"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// TestRedisPresence 两个节点共享在线状态，节点宕机后其在线记录过期
+func TestRedisPresence(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	ttl := 30 * time.Second
+	nodeA, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-a", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeB, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-b", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodeB.Close()
+
+	if err = nodeA.Online("user-1"); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := nodeB.Nodes("user-1")
+	if err != nil || len(nodes) != 1 || nodes[0] != "node-a" {
+		t.Fatalf("nodes = %v, err = %v, want [node-a]", nodes, err)
+	}
+
+	_ = nodeA.Offline("user-1")
+	if nodes, _ = nodeB.Nodes("user-1"); len(nodes) != 0 {
+		t.Fatalf("nodes = %v after offline, want none", nodes)
+	}
+
+	// node-a 宕机：不再续期，存活标记过期后其在线记录失效
+	_ = nodeA.Online("user-2")
+	mr.FastForward(ttl + time.Second)
+	_ = nodeB.Online("user-3") // 查询节点自身的记录不受存活标记影响
+	if nodes, _ = nodeB.Nodes("user-2"); len(nodes) != 0 {
+		t.Fatalf("nodes = %v after node-a died, want none", nodes)
+	}
+	if nodes, _ = nodeB.Nodes("user-3"); len(nodes) != 1 || nodes[0] != "node-b" {
+		t.Fatalf("nodes = %v, want [node-b]", nodes)
+	}
+}
+
+// TestRedisPresenceNodeDown 节点宕机后其上的用户变为离线，记录最后在线时间并通知状态变化
+func TestRedisPresenceNodeDown(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	ttl := 30 * time.Second
+	nodeA, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-a", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = nodeA.Online("user-1")
+	mr.FastForward(ttl + time.Second)
+
+	// 新启动的节点清除已宕机节点的在线记录
+	nodeB, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-b", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodeB.Close()
+	status, err := nodeB.Status("user-1")
+	if err != nil || status.Status != constant.PRESENCE_OFFLINE || status.LastSeen == 0 {
+		t.Fatalf("status = %+v, err = %v, want offline with last seen", status, err)
+	}
+
+	// 查询时发现的已宕机节点同样记录最后在线时间，并通知状态变化
+	nodeC, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-c", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = nodeC.Online("user-2")
+	mr.FastForward(ttl + time.Second)
+	changed := make(chan string, 1)
+	nodeB.OnChange(func(userUuid string) { changed <- userUuid })
+	if status, _ = nodeB.Status("user-2"); status.Status != constant.PRESENCE_OFFLINE || status.LastSeen == 0 {
+		t.Fatalf("status = %+v, want offline with last seen", status)
+	}
+	select {
+	case user := <-changed:
+		if user != "user-2" {
+			t.Fatalf("changed %s, want user-2", user)
+		}
+	default:
+		t.Fatal("no change notified")
+	}
+}
+
+// TestPresenceStatus 在线、离开、离线状态的切换，以及同一次状态变化只推送一次
+func TestPresenceStatus(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	nodeA, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-a", 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodeA.Close()
+	nodeB, err := presence.NewRedisRegistry(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "node-b", 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodeB.Close()
+
+	_ = nodeA.Online("user-1")
+	_ = nodeA.SetAway("user-1", true)
+	if status, _ := nodeB.Status("user-1"); status.Status != constant.PRESENCE_AWAY {
+		t.Fatalf("status = %s, want away", status.Status)
+	}
+
+	// 重新上线清除离开标记
+	_ = nodeA.Online("user-1")
+	if status, _ := nodeB.Status("user-1"); status.Status != constant.PRESENCE_ONLINE {
+		t.Fatalf("status = %s, want online", status.Status)
+	}
+
+	_ = nodeA.Offline("user-1")
+	status, _ := nodeB.Status("user-1")
+	if status.Status != constant.PRESENCE_OFFLINE || status.LastSeen == 0 {
+		t.Fatalf("status = %+v, want offline with last seen", status)
+	}
+
+	// 两个节点察觉到同一次变化，只有一个节点需要推送
+	changedA, _ := nodeA.Announce("user-1", constant.PRESENCE_OFFLINE)
+	changedB, _ := nodeB.Announce("user-1", constant.PRESENCE_OFFLINE)
+	if !changedA || changedB {
+		t.Fatalf("changed = %v, %v, want true, false", changedA, changedB)
+	}
+}
+
+func TestLocalPresenceMultiNode(t *testing.T) {
+	// local注册表只知道本节点的用户，不能与多节点的消息队列一起使用
+	for _, channel := range []string{constant.KAFKA, constant.REDIS} {
+		conf := config.TomlConfig{MsgChannelType: config.MsgChannelType{ChannelType: channel}, Presence: config.PresenceConfig{Registry: constant.LOCAL}}
+		if _, err := presence.New(conf); err == nil {
+			t.Fatalf("local registry accepted with %s bus", channel)
+		}
+	}
+	conf := config.TomlConfig{MsgChannelType: config.MsgChannelType{ChannelType: constant.GO_CHANNEL}, Presence: config.PresenceConfig{Registry: constant.LOCAL}}
+	if _, err := presence.New(conf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/model"
+	"chat-room/internal/permission"
+	"chat-room/pkg/common/constant"
+)
+
+func TestPermissionMatrix(t *testing.T) {
+	member, admin, owner := int16(constant.GROUP_ROLE_MEMBER), int16(constant.GROUP_ROLE_ADMIN), int16(constant.GROUP_ROLE_OWNER)
+	tests := []struct {
+		action  string
+		role    int16
+		allowed bool
+	}{
+		{permission.GROUP_ACTION_INVITE, member, true},
+		{permission.GROUP_ACTION_LINK, member, false},
+		{permission.GROUP_ACTION_LINK, admin, true},
+		{permission.GROUP_ACTION_RENAME, member, false},
+		{permission.GROUP_ACTION_RENAME, admin, true},
+		{permission.GROUP_ACTION_NOTICE, member, false},
+		{permission.GROUP_ACTION_NOTICE, admin, true},
+		{permission.GROUP_ACTION_KICK, member, false},
+		{permission.GROUP_ACTION_KICK, admin, true},
+		{permission.GROUP_ACTION_MUTE, member, false},
+		{permission.GROUP_ACTION_MUTE, admin, true},
+		{permission.GROUP_ACTION_PIN, member, false},
+		{permission.GROUP_ACTION_PIN, admin, true},
+		{permission.GROUP_ACTION_PROMOTE, admin, false},
+		{permission.GROUP_ACTION_PROMOTE, owner, true},
+		{permission.GROUP_ACTION_TRANSFER, admin, false},
+		{permission.GROUP_ACTION_TRANSFER, owner, true},
+		{permission.GROUP_ACTION_DISSOLVE, admin, false},
+		{permission.GROUP_ACTION_DISSOLVE, owner, true},
+		{"unknown", owner, false},
+	}
+	for _, tt := range tests {
+		err := permission.Check(tt.role, tt.action)
+		if (err == nil) != tt.allowed {
+			t.Errorf("Check(%d, %s) = %v, want allowed %v", tt.role, tt.action, err, tt.allowed)
+		}
+	}
+}
+
+func TestPermissionManage(t *testing.T) {
+	tests := []struct {
+		role, target int16
+		allowed      bool
+	}{
+		{constant.GROUP_ROLE_OWNER, constant.GROUP_ROLE_ADMIN, true},
+		{constant.GROUP_ROLE_OWNER, constant.GROUP_ROLE_MEMBER, true},
+		{constant.GROUP_ROLE_ADMIN, constant.GROUP_ROLE_MEMBER, true},
+		// 不能管理同级或更高角色的成员
+		{constant.GROUP_ROLE_ADMIN, constant.GROUP_ROLE_ADMIN, false},
+		{constant.GROUP_ROLE_ADMIN, constant.GROUP_ROLE_OWNER, false},
+		{constant.GROUP_ROLE_MEMBER, constant.GROUP_ROLE_MEMBER, false},
+	}
+	for _, tt := range tests {
+		err := permission.CheckManage(tt.role, tt.target)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckManage(%d, %d) = %v, want allowed %v", tt.role, tt.target, err, tt.allowed)
+		}
+	}
+}
+
+func TestPermissionRole(t *testing.T) {
+	group := &model.Group{ID: 1, UserId: 10}
+	tests := []struct {
+		name   string
+		member model.GroupMember
+		role   int16
+	}{
+		{"owner", model.GroupMember{UserId: 10, Role: constant.GROUP_ROLE_OWNER}, constant.GROUP_ROLE_OWNER},
+		// 功能上线前创建的群，成员表中的群主没有角色
+		{"legacy owner", model.GroupMember{UserId: 10}, constant.GROUP_ROLE_OWNER},
+		{"admin", model.GroupMember{UserId: 11, Role: constant.GROUP_ROLE_ADMIN}, constant.GROUP_ROLE_ADMIN},
+		{"member", model.GroupMember{UserId: 12}, constant.GROUP_ROLE_MEMBER},
+		// 群主以groups.user_id为准，成员表中残留的群主角色不生效
+		{"stale owner", model.GroupMember{UserId: 13, Role: constant.GROUP_ROLE_OWNER}, constant.GROUP_ROLE_MEMBER},
+	}
+	for _, tt := range tests {
+		if role := permission.Role(group, &tt.member); role != tt.role {
+			t.Errorf("%s: Role = %d, want %d", tt.name, role, tt.role)
+		}
+	}
+}
+
+func TestPermissionSend(t *testing.T) {
+	now := time.Now()
+	group := &model.Group{ID: 1, UserId: 10}
+	allMuted := &model.Group{ID: 1, UserId: 10, AllMuted: true}
+	tests := []struct {
+		name    string
+		group   *model.Group
+		member  model.GroupMember
+		allowed bool
+	}{
+		{"member", group, model.GroupMember{UserId: 12}, true},
+		{"muted forever", group, model.GroupMember{UserId: 12, Mute: 1}, false},
+		{"muted until later", group, model.GroupMember{UserId: 12, Mute: 1, MuteUntil: now.Add(time.Minute).UnixMilli()}, false},
+		{"mute expired", group, model.GroupMember{UserId: 12, Mute: 1, MuteUntil: now.Add(-time.Minute).UnixMilli()}, true},
+		{"all muted member", allMuted, model.GroupMember{UserId: 12}, false},
+		{"all muted admin", allMuted, model.GroupMember{UserId: 11, Role: constant.GROUP_ROLE_ADMIN}, true},
+		{"all muted legacy owner", allMuted, model.GroupMember{UserId: 10}, true},
+		// 被单独禁言的管理员在全员禁言时也不能发言
+		{"all muted muted admin", allMuted, model.GroupMember{UserId: 11, Role: constant.GROUP_ROLE_ADMIN, Mute: 1}, false},
+	}
+	for _, tt := range tests {
+		err := permission.CheckSend(tt.group, &tt.member, now)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s: CheckSend = %v, want allowed %v", tt.name, err, tt.allowed)
+		}
+	}
+}
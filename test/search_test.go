@@ -0,0 +1,86 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/search"
+	"chat-room/pkg/common/constant"
+)
+
+// TestMemorySearch 进程内索引按会话范围、发送者和游标过滤，撤回和编辑后索引同步更新
+func TestMemorySearch(t *testing.T) {
+	engine := search.NewMemoryEngine()
+	now := time.Now()
+	docs := []search.Document{
+		{ID: 1, MessageType: constant.MESSAGE_TYPE_USER, FromUserId: 1, ToUserId: 2, ContentType: constant.TEXT, Content: "明天一起吃火锅", CreatedAt: now},
+		{ID: 2, MessageType: constant.MESSAGE_TYPE_USER, FromUserId: 3, ToUserId: 4, ContentType: constant.TEXT, Content: "火锅店已经订好了", CreatedAt: now},
+		{ID: 3, MessageType: constant.MESSAGE_TYPE_GROUP, FromUserId: 3, ToUserId: 10, ContentType: constant.TEXT, Content: "群里谁想吃火锅", CreatedAt: now},
+		{ID: 4, MessageType: constant.MESSAGE_TYPE_USER, FromUserId: 2, ToUserId: 1, ContentType: constant.TEXT, Content: "Hotpot is fine", CreatedAt: now},
+	}
+	for _, doc := range docs {
+		if err := engine.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := search.Query{Keyword: "火锅", UserId: 1, Direct: true, GroupIds: []int32{10}, Size: 10}
+	assertIds(t, engine, query, 3, 1)
+
+	query.FromUserId = 3
+	assertIds(t, engine, query, 3)
+	query.FromUserId = 0
+
+	// 分页：每页一条，第二页从上一页最后一条继续
+	query.Size = 1
+	ids, hasMore, _ := engine.Search(query)
+	if len(ids) != 1 || ids[0] != 3 || !hasMore {
+		t.Fatalf("first page = %v, hasMore = %v", ids, hasMore)
+	}
+	query.Before = ids[0]
+	query.Size = 10
+	assertIds(t, engine, query, 1)
+	query.Before = 0
+
+	// 单个字以及不区分大小写
+	assertIds(t, engine, search.Query{Keyword: "吃", UserId: 1, Direct: true, GroupIds: []int32{10}, Size: 10}, 3, 1)
+	assertIds(t, engine, search.Query{Keyword: "HOTPOT", UserId: 1, Direct: true, Size: 10}, 4)
+
+	_ = engine.Remove(3)
+	assertIds(t, engine, query, 1)
+
+	docs[0].Content = "明天一起吃烧烤"
+	_ = engine.Index(docs[0])
+	assertIds(t, engine, query)
+}
+
+func assertIds(t *testing.T, engine search.Engine, query search.Query, want ...int32) {
+	t.Helper()
+	ids, _, err := engine.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("search %q = %v, want %v", query.Keyword, ids, want)
+	}
+	for i := range ids {
+		if ids[i] != want[i] {
+			t.Fatalf("search %q = %v, want %v", query.Keyword, ids, want)
+		}
+	}
+}
+
+// TestHighlight 高亮摘要标记全部命中并转义其余内容
+func TestHighlight(t *testing.T) {
+	got := search.Highlight("<b>Go</b> and go", "go")
+	want := "&lt;b&gt;<em>Go</em>&lt;/b&gt; and <em>go</em>"
+	if got != want {
+		t.Fatalf("highlight = %q, want %q", got, want)
+	}
+
+	got = search.Highlight("这是一段很长很长很长很长很长很长很长很长的前缀，然后出现了关键字，后面还有很长很长很长很长很长很长很长很长的内容", "关键字")
+	want = "...长很长很长很长很长很长的前缀，然后出现了<em>关键字</em>，后面还有很长很长很长很长很长很长很长很..."
+	if got != want {
+		t.Fatalf("highlight = %q, want %q", got, want)
+	}
+}
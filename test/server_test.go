@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/bus"
+	"chat-room/internal/server"
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/global/log"
+	"chat-room/pkg/protocol"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+// register 将设备注册到服务端，并读掉服务端发送的欢迎消息
+func register(t *testing.T, s *server.Server, name string, device string) *server.Client {
+	client := &server.Client{Name: name, Id: device, Send: make(chan []byte, 8)}
+	s.Register <- client
+	if _, ok := receive(client.Send); !ok {
+		t.Fatalf("%s: no welcome message", name)
+	}
+	return client
+}
+
+func TestServerDelivery(t *testing.T) {
+	if log.Logger == nil {
+		log.Logger = zap.NewNop()
+	}
+	s := server.NewServer()
+	b := bus.NewChannelBus()
+	defer b.Close()
+	if err := s.SetBus(b); err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+
+	receiver := register(t, s, "receiver", "device-1")
+	other := register(t, s, "other", "device-1")
+	// 下线后在线列表为空，防抖结束后的在线状态推送不会查询数据库
+	defer func() {
+		s.Offline <- receiver
+		s.Offline <- other
+	}()
+
+	err := s.Publish(&protocol.Message{
+		From:        "sender",
+		To:          "receiver",
+		Content:     "hello",
+		ContentType: constant.TEXT,
+		MessageType: constant.MESSAGE_TYPE_USER,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, ok := receive(receiver.Send)
+	if !ok {
+		t.Fatal("message not delivered")
+	}
+	msg := &protocol.Message{}
+	if err = proto.Unmarshal(data, msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "hello" || msg.From != "sender" {
+		t.Fatalf("got from=%s content=%s, want sender/hello", msg.From, msg.Content)
+	}
+
+	// 单聊消息不会投递给其他用户
+	select {
+	case data = <-other.Send:
+		t.Fatalf("unexpected message to other user: %v", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
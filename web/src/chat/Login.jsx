@@ -31,6 +31,8 @@ class Login extends React.Component {
             .then(response => {
                 message.success("登录成功！");
                 localStorage.username = response.data.username
+                localStorage.token = response.data.accessToken
+                localStorage.refreshToken = response.data.refreshToken
                 this.props.history.push("panel/" + response.data.uuid)
             });
     };
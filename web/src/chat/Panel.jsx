@@ -98,7 +98,7 @@ class Panel extends React.Component {
         console.log("to connect...")
         peer = new RTCPeerConnection();
         var image = document.getElementById('receiver');
-        socket = new WebSocket("ws://" + Params.IP_PORT + "/socket.io?user=" + this.props.match.params.user)
+        socket = new WebSocket("ws://" + Params.IP_PORT + "/socket.io?user=" + this.props.match.params.user + "&token=" + localStorage.token)
 
         socket.onopen = () => {
             heartCheck.start()
@@ -121,6 +121,11 @@ class Panel extends React.Component {
                     return;
                 }
 
+                // 好友在线状态变化、服务端保存回执、送达/已读回执、同步结果、错误、撤回/删除/编辑事件，暂不展示
+                if (["presence", "ack", "delivered", "read", "sync", "error", "recall", "delete", "edit", "react", "unreact", "pin", "unpin", "remove", "dissolve"].includes(messagePB.type)) {
+                    return;
+                }
+
                 // 接受语音电话或者视频电话 webrtc
                 if (messagePB.type === Constant.MESSAGE_TRANS_TYPE) {
                     this.dealWebRtcMessage(messagePB);
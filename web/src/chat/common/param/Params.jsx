@@ -17,7 +17,7 @@ export const MESSAGE_URL = HOST + '/message'
 
 export const GROUP_LIST_URL = HOST + '/group'
 export const GROUP_USER_URL = HOST + '/group/user/'
-export const GROUP_JOIN_URL = HOST + '/group/join/'
+export const GROUP_REDEEM_URL = HOST + '/group/redeem/'
 
 export const FILE_URL = HOST + '/file'
 
@@ -59,7 +59,8 @@ class UserList extends React.Component {
         axiosGet(Params.MESSAGE_URL, data)
             .then(response => {
                 let comments = []
-                let data = response.data
+                // 聊天记录分页返回，打开聊天窗口时获取最近的一页
+                let data = response.data && response.data.messages
                 if (null == data) {
                     data = []
                 }
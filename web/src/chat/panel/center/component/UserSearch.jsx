@@ -25,6 +25,7 @@ class UserSearch extends React.Component {
         this.state = {
             showCreateGroup: false,
             hasUser: false,
+            inviteToken: '',
             queryUser: {
                 username: '',
                 nickname: '',
@@ -92,14 +93,17 @@ class UserSearch extends React.Component {
             });
     };
 
+    /**
+     * 通过邀请链接加入群
+     */
     joinGroup = () => {
-        // /group/join/:userUid/:groupUuid
-        axiosPostBody(Params.GROUP_JOIN_URL + localStorage.uuid + "/" + this.state.queryUser.groupUuid)
+        // /group/redeem/:token
+        axiosPostBody(Params.GROUP_REDEEM_URL + this.state.inviteToken.trim())
             .then(_response => {
                 message.success("添加成功")
-                // this.fetchUserList()
                 this.setState({
-                    hasUser: false
+                    hasUser: false,
+                    inviteToken: '',
                 });
             });
     }
@@ -183,8 +187,10 @@ class UserSearch extends React.Component {
                     <Button type='primary' onClick={this.addUser} disabled={this.state.queryUser.username == null || this.state.queryUser.username === ''}>添加用户</Button>
                     <br /><br /><hr /><br /><br />
 
-                    <p>群信息：{this.state.queryUser.groupName}</p>
-                    <Button type='primary' onClick={this.joinGroup} disabled={this.state.queryUser.groupUuid == null || this.state.queryUser.groupUuid === ''}>添加群</Button>
+                    <p>群邀请链接：</p>
+                    <Input allowClear placeholder="邀请令牌" value={this.state.inviteToken} onChange={e => this.setState({ inviteToken: e.target.value })} />
+                    <br /><br />
+                    <Button type='primary' onClick={this.joinGroup} disabled={this.state.inviteToken.trim() === ''}>添加群</Button>
                 </Modal>
 
                 <Modal title="创建群" visible={this.state.showCreateGroup} onCancel={this.handleCancelGroup} onOk={this.createGroup} okText="创建群">
@@ -144,6 +144,7 @@ class UserInfo extends React.Component {
                         className="avatar-uploader"
                         showUploadList={false}
                         action={Params.FILE_URL}
+                        headers={{ [Params.AUTH_HEADER_KEY]: Params.TOKEN_PREFIX + localStorage.token }}
                         beforeUpload={beforeUpload}
                         onChange={this.handleChange}
                         data={{ uuid: this.props.user.uuid }}
@@ -126,7 +126,7 @@ class ChatDetails extends React.Component {
                                     style={{ paddingLeft: 30 }}
                                     avatar={<Avatar src={Params.HOST + "/file/" + item.avatar} />}
                                     title={item.username}
-                                    description=""
+                                    description={item.role === 2 ? "群主" : item.role === 1 ? "管理员" : ""}
                                 />
                             </List.Item>
                         )}
@@ -3,12 +3,13 @@ import qs from 'qs'
 import {
     message
 } from 'antd';
+import * as Params from '../common/param/Params'
 
 function axiosPost(url, data, options = { dealError: false }) {
     return new Promise((resolve, reject) => {
         axios.post(url, qs.stringify(data), {
             headers: {
-                // "Authorization": Params.TOKEN_PREFIX + localStorage.token,
+                "Authorization": Params.TOKEN_PREFIX + localStorage.token,
                 'content-type': 'application/x-www-form-urlencoded'
             }
         }).then(response => {
@@ -31,7 +32,7 @@ function axiosPostBody(url, data, options = { dealError: false }) {
     return new Promise((resolve, reject) => {
         axios.post(url, data, {
             headers: {
-                // "Authorization": Params.TOKEN_PREFIX + localStorage.token,
+                "Authorization": Params.TOKEN_PREFIX + localStorage.token,
             }
         }).then(response => {
             if (response.data.code === 0) {
@@ -53,7 +54,7 @@ function axiosPut(url, data = {}, options = { dealError: false }) {
     return new Promise((resolve, reject) => {
         axios.put(url, data, {
             headers: {
-                // "Authorization": Params.TOKEN_PREFIX + localStorage.token
+                "Authorization": Params.TOKEN_PREFIX + localStorage.token
             }
         }).then(response => {
             if (response.data.code === 0) {
@@ -78,7 +79,7 @@ function axiosGet(url, data = {}, options = { dealError: false }) {
                 ...data,
             },
             headers: {
-                // "Authorization": Params.TOKEN_PREFIX + localStorage.token
+                "Authorization": Params.TOKEN_PREFIX + localStorage.token
             }
         }).then(response => {
             if (response.data.code === 0) {